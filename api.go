@@ -6,34 +6,50 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
-
-	log "github.com/sirupsen/logrus"
+	"sync"
 
 	"github.com/thecodeteam/goscaleio/api"
 	types "github.com/thecodeteam/goscaleio/types/v1"
 )
 
 var (
-	accHeader string
-	conHeader string
-
 	errNilReponse = errors.New("nil response from API")
 	errBodyRead   = errors.New("error reading body")
 	errNoLink     = errors.New("Error: problem finding link")
-
-	debug, _    = strconv.ParseBool(os.Getenv("GOSCALEIO_DEBUG"))
-	showHTTP, _ = strconv.ParseBool(os.Getenv("GOSCALEIO_SHOWHTTP"))
 )
 
 type Client struct {
 	configConnect *ConfigConnect
 	api           api.Client
+
+	// mu guards configConnect and the version headers, which are
+	// replaced by Authenticate and updateVersion.
+	mu        sync.RWMutex
+	accHeader string
+	conHeader string
+
+	// authGen counts successful logins and is guarded by mu. Requests
+	// record it so a rejected request can tell whether the token it used
+	// has already been replaced.
+	authGen uint64
+
+	// authMu serializes logins so concurrent callers do not interleave
+	// token updates.
+	authMu sync.Mutex
+
+	metrics api.Metrics
+	tracer  api.Tracer
+	logger  api.Logger
+
+	// listings caches the listings lookups fall back to.
+	listings listingCache
 }
 
 type Cluster struct {
@@ -51,10 +67,10 @@ type ClientPersistent struct {
 	client        *Client
 }
 
-func (c *Client) getVersion() (string, error) {
+func (c *Client) getVersion(ctx context.Context) (string, error) {
 
 	resp, err := c.api.DoAndGetResponseBody(
-		context.Background(), http.MethodGet, "/api/version", nil, nil)
+		ctx, http.MethodGet, "/api/version", nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -80,65 +96,160 @@ func (c *Client) getVersion() (string, error) {
 	return version, nil
 }
 
-func (c *Client) updateVersion() error {
+func (c *Client) updateVersion(ctx context.Context) error {
 
-	version, err := c.getVersion()
+	version, err := c.getVersion(ctx)
 	if err != nil {
 		return err
 	}
-	c.configConnect.Version = version
 
-	accHeader = api.HeaderValContentTypeJSON
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	configConnect := *c.configConnect
+	configConnect.Version = version
+	c.configConnect = &configConnect
+	c.setVersionHeaders(version)
+
+	return nil
+}
+
+// setVersionHeaders sets the Accept and Content-Type headers sent with
+// every request to match version. The caller must hold c.mu.
+func (c *Client) setVersionHeaders(version string) {
+	c.accHeader = api.HeaderValContentTypeJSON
 	if version != "" {
-		accHeader = accHeader + ";version=" + version
+		c.accHeader = c.accHeader + ";version=" + version
 	}
-	conHeader = accHeader
+	c.conHeader = c.accHeader
+}
 
-	return nil
+func (c *Client) getConfigConnect() *ConfigConnect {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.configConnect
+}
+
+func (c *Client) getHeaders() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	headers := make(map[string]string, 2)
+	headers[api.HeaderKeyAccept] = c.accHeader
+	headers[api.HeaderKeyContentType] = c.conHeader
+	return headers
 }
 
 func (c *Client) Authenticate(configConnect *ConfigConnect) (Cluster, error) {
+	return c.AuthenticateCtx(context.Background(), configConnect)
+}
+
+func (c *Client) AuthenticateCtx(
+	ctx context.Context, configConnect *ConfigConnect) (Cluster, error) {
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	// keep a private copy so that later version updates never write to
+	// a struct the caller or another goroutine may be reading
+	cc := *configConnect
+	c.mu.Lock()
+	cc.Version = c.configConnect.Version
+	c.configConnect = &cc
+	c.mu.Unlock()
+
+	return Cluster{}, c.login(ctx, &cc)
+}
+
+// reauthenticate logs in again with the last credentials passed to
+// Authenticate after a request made at authentication generation gen was
+// rejected. If another goroutine has logged in since then it returns
+// right away so that only one login happens per expired token.
+func (c *Client) reauthenticate(ctx context.Context, gen uint64) error {
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.getAuthGeneration() != gen {
+		c.logger.Log(ctx, api.LogDebug, "token already refreshed", nil)
+		return nil
+	}
+
+	c.logger.Log(ctx, api.LogInfo, "Need to re-auth", nil)
+	if c.metrics != nil {
+		c.metrics.IncReauth()
+	}
+	if c.tracer == nil {
+		return c.login(ctx, c.getConfigConnect())
+	}
 
-	configConnect.Version = c.configConnect.Version
-	c.configConnect = configConnect
+	ctx, span := c.tracer.Start(ctx, "goscaleio reauthenticate")
+	defer span.End()
+	err := c.login(ctx, c.getConfigConnect())
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
 
-	c.api.SetToken("")
+// login exchanges the credentials in cc for a token. The caller must hold
+// c.authMu.
+func (c *Client) login(ctx context.Context, cc *ConfigConnect) error {
 
 	headers := make(map[string]string, 1)
 	headers["Authorization"] = "Basic " + basicAuth(
-		configConnect.Username, configConnect.Password)
+		cc.Username, cc.Password)
 
 	resp, err := c.api.DoAndGetResponseBody(
-		context.Background(), http.MethodGet, "api/login", headers, nil)
+		ctx, http.MethodGet, "api/login", headers, nil)
 	if err != nil {
-		doLog(log.WithError(err).Error, "")
-		return Cluster{}, err
+		c.logger.Log(ctx, api.LogError, "login failed",
+			api.Fields{"error": err})
+		return err
 	}
 	defer resp.Body.Close()
 
 	// parse the response
 	switch {
 	case resp == nil:
-		return Cluster{}, errNilReponse
+		return errNilReponse
+	case resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusForbidden:
+		c.api.SetToken("")
+		return &wrappedError{
+			sentinel: ErrAuthentication,
+			err:      c.api.ParseJSONError(resp),
+		}
 	case !(resp.StatusCode >= 200 && resp.StatusCode <= 299):
-		return Cluster{}, c.api.ParseJSONError(resp)
+		c.api.SetToken("")
+		return c.api.ParseJSONError(resp)
 	}
 
 	token, err := extractString(resp)
 	if err != nil {
-		return Cluster{}, nil
+		return err
 	}
 
 	c.api.SetToken(token)
 
-	if c.configConnect.Version == "" {
-		err = c.updateVersion()
+	c.mu.Lock()
+	c.authGen++
+	c.mu.Unlock()
+
+	if cc.Version == "" {
+		err = c.updateVersion(ctx)
 		if err != nil {
-			return Cluster{}, errors.New("error getting version of ScaleIO")
+			return errors.New("error getting version of ScaleIO")
 		}
 	}
 
-	return Cluster{}, nil
+	return nil
+}
+
+func (c *Client) getAuthGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authGen
 }
 
 func basicAuth(username, password string) string {
@@ -146,37 +257,71 @@ func basicAuth(username, password string) string {
 	return base64.StdEncoding.EncodeToString([]byte(auth))
 }
 
+// replayableBody returns a function that yields body for every attempt of
+// a request. Bodies that are read as a stream are buffered so they can be
+// sent again after a re-login.
+func replayableBody(body interface{}) (func() interface{}, error) {
+	r, ok := body.(io.Reader)
+	if !ok {
+		return func() interface{} { return body }, nil
+	}
+	if rc, ok := r.(io.Closer); ok {
+		defer rc.Close()
+	}
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errBodyRead
+	}
+	return func() interface{} {
+		return ioutil.NopCloser(bytes.NewReader(buf))
+	}, nil
+}
+
+func isUnauthorized(err error) bool {
+	e, ok := err.(*types.Error)
+	return ok && e.HTTPStatusCode == http.StatusUnauthorized
+}
+
 func (c *Client) getJSONWithRetry(
+	ctx context.Context,
 	method, uri string,
 	body, resp interface{}) error {
 
-	headers := make(map[string]string, 2)
-	headers[api.HeaderKeyAccept] = accHeader
-	headers[api.HeaderKeyContentType] = conHeader
+	headers := c.getHeaders()
+	nextBody, err := replayableBody(body)
+	if err != nil {
+		return err
+	}
+	defer c.listings.invalidate(ctx, method)
 
-	err := c.api.DoWithHeaders(
-		context.Background(), method, uri, headers, body, resp)
+	gen := c.getAuthGeneration()
+	err = c.api.DoWithHeaders(
+		ctx, method, uri, headers, nextBody(), resp)
 	if err == nil {
 		return nil
 	}
 
 	// check if we need to authenticate
-	if e, ok := err.(*types.Error); ok {
-		doLog(log.WithError(err).Debug, fmt.Sprintf("Got JSON error: %+v", e))
-		if e.HTTPStatusCode == 401 {
-			doLog(log.Info, "Need to re-auth")
-			// Authenticate then try again
-			if _, err := c.Authenticate(c.configConnect); err != nil {
-				return fmt.Errorf("Error Authenticating: %s", err)
-			}
-			return c.api.Do(
-				context.Background(),
-				method, uri, nil, resp)
+	if isUnauthorized(err) {
+		c.logger.Log(ctx, api.LogDebug, "Got JSON error",
+			api.Fields{"error": err})
+		// Authenticate then try again with the same request
+		if err := c.reauthenticate(ctx, gen); err != nil {
+			return err
+		}
+		err = c.api.DoWithHeaders(
+			ctx, method, uri, c.getHeaders(), nextBody(), resp)
+		if err == nil {
+			return nil
 		}
 	}
-	doLog(log.WithError(err).Error, "returning error")
+	c.logger.Log(ctx, api.LogError, "returning error", api.Fields{
+		"method": method,
+		"uri":    uri,
+		"error":  err,
+	})
 
-	return err
+	return classifyError(err)
 }
 
 func extractString(resp *http.Response) (string, error) {
@@ -194,56 +339,46 @@ func extractString(resp *http.Response) (string, error) {
 }
 
 func (c *Client) getStringWithRetry(
+	ctx context.Context,
 	method, uri string,
 	body interface{}) (string, error) {
 
-	headers := make(map[string]string, 2)
-	headers[api.HeaderKeyAccept] = accHeader
-	headers[api.HeaderKeyContentType] = conHeader
+	nextBody, err := replayableBody(body)
+	if err != nil {
+		return "", err
+	}
+	defer c.listings.invalidate(ctx, method)
 
-	checkResponse := func(resp *http.Response) (string, bool, error) {
+	getString := func() (string, error) {
+		resp, err := c.api.DoAndGetResponseBody(
+			ctx, method, uri, c.getHeaders(), nextBody())
+		if err != nil {
+			return "", err
+		}
 		defer resp.Body.Close()
 
 		// parse the response
 		switch {
 		case resp == nil:
-			return "", false, errNilReponse
-		case resp.StatusCode == 401:
-			return "", true, c.api.ParseJSONError(resp)
+			return "", errNilReponse
 		case !(resp.StatusCode >= 200 && resp.StatusCode <= 299):
-			return "", false, c.api.ParseJSONError(resp)
-		}
-
-		s, err := extractString(resp)
-		if err != nil {
-			return "", false, err
+			return "", c.api.ParseJSONError(resp)
 		}
 
-		return s, false, nil
+		return extractString(resp)
 	}
 
-	resp, err := c.api.DoAndGetResponseBody(
-		context.Background(), method, uri, headers, body)
-	if err != nil {
-		return "", err
-	}
-	s, retry, httpErr := checkResponse(resp)
-	if httpErr != nil {
-		if retry {
-			doLog(log.Info, "need to re-auth")
-			// Authenticate then try again
-			if _, err = c.Authenticate(c.configConnect); err != nil {
-				return "", fmt.Errorf("Error Authenticating: %s", err)
-			}
-			resp, err = c.api.DoAndGetResponseBody(
-				context.Background(), method, uri, headers, body)
-			if err != nil {
-				return "", err
-			}
-			s, _, err = checkResponse(resp)
-		} else {
-			return "", httpErr
+	gen := c.getAuthGeneration()
+	s, err := getString()
+	if isUnauthorized(err) {
+		// Authenticate then try again with the same request
+		if err := c.reauthenticate(ctx, gen); err != nil {
+			return "", err
 		}
+		s, err = getString()
+	}
+	if err != nil {
+		return "", classifyError(err)
 	}
 
 	return s, nil
@@ -257,12 +392,65 @@ func (c *Client) GetToken() string {
 	return c.api.GetToken()
 }
 
+// Endpoint returns the gateway the client currently sends requests to, or
+// an empty string if its api.Client does not report it.
+func (c *Client) Endpoint() string {
+	if e, ok := c.api.(interface{ Endpoint() string }); ok {
+		return e.Endpoint()
+	}
+	return ""
+}
+
 func NewClient() (client *Client, err error) {
-	return NewClientWithArgs(
-		os.Getenv("GOSCALEIO_ENDPOINT"),
+	// GOSCALEIO_ENDPOINT may list several gateways separated by commas
+	endpoints := strings.Split(os.Getenv("GOSCALEIO_ENDPOINT"), ",")
+	for i := range endpoints {
+		endpoints[i] = strings.TrimSpace(endpoints[i])
+	}
+
+	return NewClientWithOptions(
+		endpoints[0],
 		os.Getenv("GOSCALEIO_VERSION"),
-		os.Getenv("GOSCALEIO_INSECURE") == "true",
-		os.Getenv("GOSCALEIO_USECERTS") == "true")
+		api.ClientOptions{
+			Insecure:  os.Getenv("GOSCALEIO_INSECURE") == "true",
+			UseCerts:  os.Getenv("GOSCALEIO_USECERTS") == "true",
+			Endpoints: endpoints[1:],
+			TLS:       tlsOptionsFromEnv(),
+			Debug:     envBool("GOSCALEIO_DEBUG"),
+			ShowHTTP:  envBool("GOSCALEIO_SHOWHTTP"),
+		})
+}
+
+// envBool reports whether the environment variable name is set to a true
+// value.
+func envBool(name string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(name))
+	return v
+}
+
+// tlsOptionsFromEnv returns the TLS options set in the GOSCALEIO_CACERT,
+// GOSCALEIO_CLIENTCERT, GOSCALEIO_CLIENTKEY, GOSCALEIO_CERTFINGERPRINT
+// and GOSCALEIO_SERVERNAME environment variables, or nil if none are.
+func tlsOptionsFromEnv() *api.TLSOptions {
+	opts := &api.TLSOptions{
+		CACertFile:     os.Getenv("GOSCALEIO_CACERT"),
+		ClientCertFile: os.Getenv("GOSCALEIO_CLIENTCERT"),
+		ClientKeyFile:  os.Getenv("GOSCALEIO_CLIENTKEY"),
+		ServerName:     os.Getenv("GOSCALEIO_SERVERNAME"),
+	}
+	for _, fp := range strings.Split(
+		os.Getenv("GOSCALEIO_CERTFINGERPRINT"), ",") {
+		if fp = strings.TrimSpace(fp); fp != "" {
+			opts.PinnedFingerprints = append(opts.PinnedFingerprints, fp)
+		}
+	}
+
+	if opts.CACertFile == "" && opts.ClientCertFile == "" &&
+		opts.ClientKeyFile == "" && opts.ServerName == "" &&
+		len(opts.PinnedFingerprints) == 0 {
+		return nil
+	}
+	return opts
 }
 
 func NewClientWithArgs(
@@ -271,36 +459,50 @@ func NewClientWithArgs(
 	insecure,
 	useCerts bool) (client *Client, err error) {
 
-	if showHTTP {
-		debug = true
-	}
+	return NewClientWithOptions(endpoint, version, api.ClientOptions{
+		Insecure: insecure,
+		UseCerts: useCerts,
+		TLS:      tlsOptionsFromEnv(),
+		Debug:    envBool("GOSCALEIO_DEBUG"),
+		ShowHTTP: envBool("GOSCALEIO_SHOWHTTP"),
+	})
+}
+
+// NewClientWithOptions returns a new client for the gateway at endpoint
+// using opts to configure the underlying API client, e.g. its
+// RetryPolicy.
+func NewClientWithOptions(
+	endpoint string,
+	version string,
+	opts api.ClientOptions) (client *Client, err error) {
+
+	logger := api.NewLogger(opts)
+	opts.Logger = logger
 
 	fields := map[string]interface{}{
-		"endpoint": endpoint,
-		"insecure": insecure,
-		"useCerts": useCerts,
-		"version":  version,
-		"debug":    debug,
-		"showHTTP": showHTTP,
+		"endpoint":  endpoint,
+		"endpoints": opts.Endpoints,
+		"insecure":  opts.Insecure,
+		"useCerts":  opts.UseCerts,
+		"version":   version,
+		"debug":     opts.Debug,
+		"showHTTP":  opts.ShowHTTP,
 	}
 
-	doLog(log.WithFields(fields).Debug, "goscaleio client init")
+	logger.Log(context.Background(), api.LogDebug,
+		"goscaleio client init", fields)
 
-	if endpoint == "" {
-		doLog(log.WithFields(fields).Error, "endpoint is required")
+	if endpoint == "" && len(opts.Endpoints) == 0 {
+		logger.Log(context.Background(), api.LogError,
+			"endpoint is required", fields)
 		return nil,
 			withFields(fields, "endpoint is required")
 	}
 
-	opts := api.ClientOptions{
-		Insecure: insecure,
-		UseCerts: useCerts,
-		ShowHTTP: showHTTP,
-	}
-
-	ac, err := api.New(context.Background(), endpoint, opts, debug)
+	ac, err := api.New(context.Background(), endpoint, opts, false)
 	if err != nil {
-		doLog(log.WithError(err).Error, "Unable to create HTTP client")
+		logger.Log(context.Background(), api.LogError,
+			"Unable to create HTTP client", api.Fields{"error": err})
 		return nil, err
 	}
 
@@ -309,13 +511,11 @@ func NewClientWithArgs(
 		configConnect: &ConfigConnect{
 			Version: version,
 		},
+		metrics: opts.Metrics,
+		tracer:  opts.Tracer,
+		logger:  logger,
 	}
-
-	accHeader = api.HeaderValContentTypeJSON
-	if version != "" {
-		accHeader = accHeader + ";version=" + version
-	}
-	conHeader = accHeader
+	client.setVersionHeaders(version)
 
 	return client, nil
 }
@@ -360,12 +560,3 @@ func withFieldsE(
 
 	return fmt.Errorf("%s %s", message, b.String())
 }
-
-func doLog(
-	l func(args ...interface{}),
-	msg string) {
-
-	if debug {
-		l(msg)
-	}
-}
@@ -3,19 +3,15 @@ package api
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
 	types "github.com/thecodeteam/goscaleio/types/v1"
 )
 
@@ -94,11 +90,19 @@ type Client interface {
 }
 
 type client struct {
-	http     *http.Client
-	host     string
-	token    string
-	showHTTP bool
-	debug    bool
+	http      *http.Client
+	endpoints *endpointSet
+	showHTTP  bool
+
+	retryPolicy *RetryPolicy
+	onRequest   RequestHook
+	metrics     Metrics
+	tracer      Tracer
+	logger      Logger
+	maxLogBody  int
+
+	tokenMu sync.RWMutex
+	token   string
 }
 
 // ClientOptions are options for the API client.
@@ -113,58 +117,113 @@ type ClientOptions struct {
 	Timeout time.Duration
 
 	// ShowHTTP is a flag that indicates whether or not HTTP requests and
-	// responses should be logged to stdout
+	// responses should be logged. Credentials are always redacted.
 	ShowHTTP bool
+
+	// Debug enables logging with the standard logrus logger when Logger
+	// is not set.
+	Debug bool
+
+	// Logger receives the client's log entries. If it is nil entries are
+	// dropped unless Debug or ShowHTTP is set.
+	Logger Logger
+
+	// MaxLogBodySize is the number of bytes of every request and response
+	// body that are logged when ShowHTTP is set. Zero means
+	// DefaultMaxLogBodySize and a negative value leaves bodies out.
+	MaxLogBodySize int
+
+	// RetryPolicy specifies how requests that fail with a transient error
+	// are retried. A nil RetryPolicy sends every request only once.
+	RetryPolicy *RetryPolicy
+
+	// Endpoints are additional gateways, in order of preference, that the
+	// client fails over to when the current one cannot be reached. A new
+	// gateway rejects the previous gateway's token, so callers must be
+	// prepared to log in again on a 401.
+	Endpoints []string
+
+	// EndpointCooldown is how long a gateway that could not be reached is
+	// skipped when choosing the next one. It defaults to 30 seconds.
+	EndpointCooldown time.Duration
+
+	// OnEndpointChange, if set, is called whenever the client fails over
+	// from one gateway to another.
+	OnEndpointChange func(from, to string)
+
+	// TLS configures the CA certificates, client certificate, pinned
+	// fingerprints and server name used to connect to the gateway.
+	TLS *TLSOptions
+
+	// Transport is the RoundTripper requests are sent with. If it is set
+	// Insecure, UseCerts and TLS are ignored.
+	Transport http.RoundTripper
+
+	// Middleware wraps Transport, the first element being the first to
+	// see every request.
+	Middleware []Middleware
+
+	// OnRequest, if set, is called after every request sent to the
+	// gateway with its method, path, status and latency.
+	OnRequest RequestHook
+
+	// Metrics, if set, records the count, latency and status of every
+	// request as well as retries and re-logins.
+	Metrics Metrics
+
+	// Tracer, if set, starts a span for every request sent to the gateway
+	// as a child of the span in the request's context.
+	Tracer Tracer
 }
 
-// New returns a new API client.
+// New returns a new API client. Setting debug is the same as setting
+// opts.Debug.
 func New(
 	ctx context.Context,
 	host string,
 	opts ClientOptions,
 	debug bool) (Client, error) {
 
-	if host == "" {
+	hosts := append([]string{host}, opts.Endpoints...)
+	endpoints := newEndpointSet(
+		hosts, opts.EndpointCooldown, opts.OnEndpointChange)
+	if endpoints.len() == 0 {
 		return nil, errNewClient
 	}
 
-	host = strings.Replace(host, "/api", "", 1)
-
 	c := &client{
-		http: &http.Client{},
-		host: host,
+		http:      &http.Client{},
+		endpoints: endpoints,
 	}
 
 	if opts.Timeout != 0 {
 		c.http.Timeout = opts.Timeout
 	}
 
-	if opts.Insecure {
-		c.http.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		}
-	}
-
-	if opts.UseCerts {
-		pool, err := x509.SystemCertPool()
+	if opts.Transport != nil {
+		c.http.Transport = opts.Transport
+	} else {
+		transport, err := newTransport(opts)
 		if err != nil {
-			return nil, errSysCerts
-		}
-		c.http.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:            pool,
-				InsecureSkipVerify: opts.Insecure,
-			},
+			return nil, err
 		}
+		c.http.Transport = transport
 	}
 
-	if opts.ShowHTTP {
-		c.showHTTP = true
+	if len(opts.Middleware) > 0 {
+		c.http.Transport = chainMiddleware(c.http.Transport, opts.Middleware)
 	}
+	c.onRequest = opts.OnRequest
+	c.metrics = opts.Metrics
+	c.tracer = opts.Tracer
 
-	c.debug = debug
+	if debug {
+		opts.Debug = true
+	}
+	c.logger = NewLogger(opts)
+	c.showHTTP = opts.ShowHTTP
+	c.maxLogBody = opts.MaxLogBodySize
+	c.retryPolicy = opts.RetryPolicy
 
 	return c, nil
 }
@@ -248,9 +307,12 @@ func (c *client) DoWithHeaders(
 		}
 		dec := json.NewDecoder(res.Body)
 		if err = dec.Decode(resp); err != nil && err != io.EOF {
-			c.doLog(log.WithError(err).Error,
-				fmt.Sprintf("Unable to decode response into %+v",
-					resp))
+			c.logger.Log(ctx, LogError, "unable to decode response", Fields{
+				"method": method,
+				"uri":    uri,
+				"type":   fmt.Sprintf("%T", resp),
+				"error":  err,
+			})
 			return err
 		}
 	default:
@@ -267,107 +329,207 @@ func (c *client) DoAndGetResponseBody(
 	body interface{}) (*http.Response, error) {
 
 	var (
-		err                error
-		req                *http.Request
-		res                *http.Response
-		ubf                = &bytes.Buffer{}
-		luri               = len(uri)
-		hostEndsWithSlash  = endsWithSlash(c.host)
-		uriBeginsWithSlash = beginsWithSlash(uri)
+		err         error
+		req         *http.Request
+		res         *http.Response
+		payload     []byte
+		stream      io.ReadCloser
+		contentType string
 	)
 
-	ubf.WriteString(c.host)
-
-	if !hostEndsWithSlash && (luri > 0) {
-		ubf.WriteString("/")
-	}
-
-	if luri > 0 {
-		if uriBeginsWithSlash {
-			ubf.WriteString(uri[1:])
-		} else {
-			ubf.WriteString(uri)
-		}
-	}
-
-	u, err := url.Parse(ubf.String())
-	if err != nil {
-		return nil, err
-	}
-
-	var isContentTypeSet bool
-
 	// marshal the message body (assumes json format)
 	if r, ok := body.(io.ReadCloser); ok {
-		req, err = http.NewRequest(method, u.String(), r)
+		stream = r
 		defer r.Close()
-		if v, ok := headers[HeaderKeyContentType]; ok {
-			req.Header.Set(HeaderKeyContentType, v)
-		} else {
-			req.Header.Set(
-				HeaderKeyContentType, headerValContentTypeBinaryOctetStream)
-		}
-		isContentTypeSet = true
+		contentType = headerValContentTypeBinaryOctetStream
 	} else if body != nil {
 		buf := &bytes.Buffer{}
 		enc := json.NewEncoder(buf)
 		if err = enc.Encode(body); err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest(method, u.String(), buf)
-		if v, ok := headers[HeaderKeyContentType]; ok {
-			req.Header.Set(HeaderKeyContentType, v)
+		payload = buf.Bytes()
+		contentType = HeaderValContentTypeJSON
+	}
+	if v, ok := headers[HeaderKeyContentType]; ok && contentType != "" {
+		contentType = v
+	}
+
+	// a streamed body cannot be replayed so it only gets one attempt
+	var (
+		maxAttempts = 1
+		idempotent  = isIdempotent(ctx, method)
+	)
+	if stream == nil {
+		maxAttempts = c.retryPolicy.maxAttempts()
+	}
+
+	attempt, failovers := 1, 0
+	for {
+		var rdr io.Reader
+		if stream != nil {
+			rdr = stream
+		} else if payload != nil {
+			rdr = bytes.NewReader(payload)
+		}
+
+		var (
+			host = c.endpoints.get()
+			u    *url.URL
+		)
+		if u, err = buildURL(host, uri); err != nil {
+			return nil, err
+		}
+
+		reqCtx, span := c.startSpan(
+			ctx, method, u.Path, host, attempt+failovers)
+		req, err = c.newRequest(
+			reqCtx, method, u.String(), headers, rdr, contentType)
+		if err != nil {
+			finishSpan(span, nil, err)
+			return nil, err
+		}
+
+		if c.showHTTP {
+			c.logRequest(reqCtx, req, payload, host, attempt+failovers)
+		}
+
+		// send the request
+		start := time.Now()
+		res, err = c.http.Do(req)
+		elapsed := time.Since(start)
+		c.observe(reqCtx, RequestInfo{
+			Method:   method,
+			Path:     u.Path,
+			Endpoint: host,
+			Duration: elapsed,
+			Attempt:  attempt + failovers,
+			Err:      err,
+		}, res)
+		if c.showHTTP && err == nil {
+			c.logResponse(reqCtx, res, host, attempt+failovers, elapsed)
+		}
+		finishSpan(span, res, err)
+
+		// move on to the next gateway if this one cannot be reached and
+		// the request can safely be sent again
+		if err != nil && stream == nil && ctx.Err() == nil &&
+			failovers < c.endpoints.len()-1 &&
+			(isDialError(err) || idempotent) {
+
+			failovers++
+			next := c.endpoints.failover(host)
+			c.logger.Log(ctx, LogWarn, "failing over to next endpoint", Fields{
+				"method": method,
+				"uri":    uri,
+				"from":   host,
+				"to":     next,
+				"error":  err,
+			})
+			continue
+		}
+
+		if attempt >= maxAttempts ||
+			!c.retryPolicy.shouldRetry(ctx, idempotent, res, err) {
+			break
+		}
+		drainBody(res)
+		if c.metrics != nil {
+			c.metrics.IncRetry(method, TemplatePath(u.Path))
+		}
+
+		delay := c.retryPolicy.backoff(attempt)
+		fields := Fields{
+			"method":  method,
+			"uri":     uri,
+			"attempt": attempt,
+			"delay":   delay,
+		}
+		if err != nil {
+			fields["error"] = err
 		} else {
-			req.Header.Set(HeaderKeyContentType, HeaderValContentTypeJSON)
+			fields["status"] = res.StatusCode
 		}
-		isContentTypeSet = true
-	} else {
-		req, err = http.NewRequest(method, u.String(), nil)
+		c.logger.Log(ctx, LogInfo, "retrying request", fields)
+
+		if err = sleepCtx(ctx, delay); err != nil {
+			return nil, err
+		}
+		attempt++
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// observe reports a request that produced res to the request hook and
+// metrics.
+func (c *client) observe(
+	ctx context.Context, info RequestInfo, res *http.Response) {
+
+	if res != nil {
+		info.StatusCode = res.StatusCode
+	}
+	if c.onRequest != nil {
+		c.onRequest(ctx, info)
 	}
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(info.Method, TemplatePath(info.Path),
+			info.StatusCode, info.Duration)
+	}
+}
+
+func (c *client) newRequest(
+	ctx context.Context,
+	method, u string,
+	headers map[string]string,
+	body io.Reader,
+	contentType string) (*http.Request, error) {
 
+	req, err := http.NewRequest(method, u, body)
 	if err != nil {
 		return nil, err
 	}
 
-	if !isContentTypeSet {
-		isContentTypeSet = req.Header.Get(HeaderKeyContentType) != ""
+	if contentType != "" {
+		req.Header.Set(HeaderKeyContentType, contentType)
 	}
 
 	// add headers to the request
 	for header, value := range headers {
-		if header == HeaderKeyContentType && isContentTypeSet {
+		if header == HeaderKeyContentType && contentType != "" {
 			continue
 		}
 		req.Header.Add(header, value)
 	}
 
-	// set the auth token
-	if c.token != "" {
-		req.SetBasicAuth("", c.token)
-	}
-
-	if c.showHTTP {
-		logRequest(ctx, req, c.doLog)
-	}
-
-	// send the request
-	req = req.WithContext(ctx)
-	if res, err = c.http.Do(req); err != nil {
-		return nil, err
-	}
-
-	if c.showHTTP {
-		logResponse(ctx, res, c.doLog)
+	// set the auth token unless the caller supplied its own credentials
+	if token := c.GetToken(); token != "" &&
+		req.Header.Get("Authorization") == "" {
+		req.SetBasicAuth("", token)
 	}
 
-	return res, err
+	return req.WithContext(ctx), nil
 }
 
 func (c *client) SetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
 	c.token = token
 }
 
+// Endpoint returns the gateway requests are currently sent to. It is not
+// part of Client so that existing implementations of Client still
+// compile.
+func (c *client) Endpoint() string {
+	return c.endpoints.get()
+}
+
 func (c *client) GetToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
 	return c.token
 }
 
@@ -383,14 +545,12 @@ func (c *client) ParseJSONError(r *http.Response) error {
 		jsonError.Message = r.Status
 	}
 
-	return jsonError
-}
-
-func (c *client) doLog(
-	l func(args ...interface{}),
-	msg string) {
-
-	if c.debug {
-		l(msg)
+	if r.Request != nil {
+		if span := SpanFromContext(r.Request.Context()); span != nil {
+			span.SetAttribute(AttrErrorCode, jsonError.ErrorCode)
+			span.RecordError(jsonError)
+		}
 	}
+
+	return jsonError
 }
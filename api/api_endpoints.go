@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultEndpointCooldown = 30 * time.Second
+
+type endpoint struct {
+	host      string
+	downUntil time.Time
+}
+
+// endpointSet is the ordered list of gateways a client talks to. Requests
+// stick to the current gateway until it fails with a connection error, at
+// which point it is marked down for the cooldown period and the next
+// healthy gateway in order becomes current.
+type endpointSet struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	current   int
+	cooldown  time.Duration
+	onChange  func(from, to string)
+}
+
+func newEndpointSet(
+	hosts []string,
+	cooldown time.Duration,
+	onChange func(from, to string)) *endpointSet {
+
+	if cooldown <= 0 {
+		cooldown = defaultEndpointCooldown
+	}
+
+	es := &endpointSet{
+		cooldown: cooldown,
+		onChange: onChange,
+	}
+
+	seen := map[string]bool{}
+	for _, host := range hosts {
+		host = strings.Replace(host, "/api", "", 1)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		es.endpoints = append(es.endpoints, &endpoint{host: host})
+	}
+
+	return es
+}
+
+func (es *endpointSet) len() int {
+	return len(es.endpoints)
+}
+
+// get returns the gateway requests should currently be sent to.
+func (es *endpointSet) get() string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.endpoints[es.current].host
+}
+
+// failover marks host as down and makes the next gateway in order that is
+// not down current, falling back to the one that has been down the
+// longest. It returns the new current gateway. If another request has
+// already moved away from host the current gateway is returned unchanged.
+func (es *endpointSet) failover(host string) string {
+	es.mu.Lock()
+
+	now := time.Now()
+	cur := es.endpoints[es.current]
+	if cur.host != host {
+		es.mu.Unlock()
+		return cur.host
+	}
+	cur.downUntil = now.Add(es.cooldown)
+
+	next := -1
+	for i := 1; i < len(es.endpoints); i++ {
+		x := (es.current + i) % len(es.endpoints)
+		ep := es.endpoints[x]
+		if now.After(ep.downUntil) {
+			next = x
+			break
+		}
+		if next == -1 ||
+			ep.downUntil.Before(es.endpoints[next].downUntil) {
+			next = x
+		}
+	}
+	if next == -1 {
+		es.mu.Unlock()
+		return host
+	}
+	es.current = next
+	to := es.endpoints[next].host
+	onChange := es.onChange
+	es.mu.Unlock()
+
+	if onChange != nil {
+		onChange(host, to)
+	}
+	return to
+}
+
+// buildURL joins host and the request uri.
+func buildURL(host, uri string) (*url.URL, error) {
+
+	var (
+		ubf                = &bytes.Buffer{}
+		luri               = len(uri)
+		hostEndsWithSlash  = endsWithSlash(host)
+		uriBeginsWithSlash = luri > 0 && beginsWithSlash(uri)
+	)
+
+	ubf.WriteString(host)
+
+	if !hostEndsWithSlash && (luri > 0) {
+		ubf.WriteString("/")
+	}
+
+	if luri > 0 {
+		if uriBeginsWithSlash {
+			ubf.WriteString(uri[1:])
+		} else {
+			ubf.WriteString(uri)
+		}
+	}
+
+	return url.Parse(ubf.String())
+}
@@ -6,68 +6,232 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
+	"regexp"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func isBinOctetBody(h http.Header) bool {
-	return h.Get(HeaderKeyContentType) == headerValContentTypeBinaryOctetStream
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+// The levels the client logs at.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// Fields are the structured fields of a log entry.
+type Fields map[string]interface{}
+
+// Logger receives the client's log entries. It is small enough to be
+// implemented on top of any logging library; NewLogrusLogger adapts
+// logrus.
+type Logger interface {
+	// Log logs msg at level. ctx is the context of the request the entry
+	// belongs to, if any.
+	Log(ctx context.Context, level LogLevel, msg string, fields Fields)
 }
 
-func logRequest(
-	ctx context.Context,
-	req *http.Request,
-	lf func(func(args ...interface{}), string)) {
+// LoggerFunc adapts an ordinary function to a Logger.
+type LoggerFunc func(
+	ctx context.Context, level LogLevel, msg string, fields Fields)
+
+// Log calls f(ctx, level, msg, fields).
+func (f LoggerFunc) Log(
+	ctx context.Context, level LogLevel, msg string, fields Fields) {
 
-	w := &bytes.Buffer{}
+	f(ctx, level, msg, fields)
+}
 
-	fmt.Fprintln(w)
-	fmt.Fprint(w, "    -------------------------- ")
-	fmt.Fprint(w, "GOSCALEIO HTTP REQUEST")
-	fmt.Fprintln(w, " -------------------------")
+// DiscardLogger is a Logger that drops every entry.
+var DiscardLogger Logger = LoggerFunc(
+	func(context.Context, LogLevel, string, Fields) {})
 
-	buf, err := httputil.DumpRequest(req, !isBinOctetBody(req.Header))
-	if err != nil {
-		return
+type logrusLogger struct {
+	l log.FieldLogger
+}
+
+// NewLogrusLogger returns a Logger that writes to l, or to the standard
+// logrus logger if l is nil.
+func NewLogrusLogger(l log.FieldLogger) Logger {
+	if l == nil {
+		l = log.StandardLogger()
 	}
+	return &logrusLogger{l: l}
+}
 
-	WriteIndented(w, buf)
-	fmt.Fprintln(w)
+func (l *logrusLogger) Log(
+	ctx context.Context, level LogLevel, msg string, fields Fields) {
 
-	lf(log.Debug, w.String())
+	entry := l.l.WithFields(log.Fields(fields))
+	switch level {
+	case LogDebug:
+		entry.Debug(msg)
+	case LogInfo:
+		entry.Info(msg)
+	case LogWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
 }
 
-func logResponse(
-	ctx context.Context,
-	res *http.Response,
-	lf func(func(args ...interface{}), string)) {
+// NewLogger returns the Logger described by opts: opts.Logger if it is
+// set, the standard logrus logger if opts.Debug or opts.ShowHTTP is set,
+// and DiscardLogger otherwise.
+func NewLogger(opts ClientOptions) Logger {
+	switch {
+	case opts.Logger != nil:
+		return opts.Logger
+	case opts.Debug || opts.ShowHTTP:
+		return NewLogrusLogger(nil)
+	}
+	return DiscardLogger
+}
+
+// DefaultMaxLogBodySize is the number of bytes of every request and
+// response body that are logged when ClientOptions.MaxLogBodySize is zero.
+const DefaultMaxLogBodySize = 4096
+
+const redacted = "[REDACTED]"
 
-	w := &bytes.Buffer{}
+// sensitiveRX matches the names of headers and JSON fields whose values
+// are credentials.
+var sensitiveRX = regexp.MustCompile(`(?i)password|passwd|token|secret`)
 
-	fmt.Fprintln(w)
-	fmt.Fprint(w, "    -------------------------- ")
-	fmt.Fprint(w, "GOSCALEIO HTTP RESPONSE")
-	fmt.Fprintln(w, " -------------------------")
+// sensitiveJSONRX matches a JSON string field whose name looks like a
+// credential. An unterminated value, as left by truncation, also matches.
+var sensitiveJSONRX = regexp.MustCompile(
+	`(?i)("[^"]*(?:password|passwd|token|secret)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*(?:"|\\?$)`)
+
+// redactHeaders returns a copy of h, with every value joined, in which
+// credentials are replaced.
+func redactHeaders(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		switch {
+		case strings.EqualFold(k, "Authorization"),
+			strings.EqualFold(k, "Proxy-Authorization"),
+			strings.EqualFold(k, "Cookie"),
+			strings.EqualFold(k, "Set-Cookie"),
+			sensitiveRX.MatchString(k):
+			m[k] = redacted
+		default:
+			m[k] = strings.Join(v, ", ")
+		}
+	}
+	return m
+}
+
+// redactBody returns the first max bytes of body, with the values of
+// JSON fields that look like credentials replaced, and whether body was
+// truncated.
+func redactBody(body []byte, max int) (string, bool) {
+	truncated := false
+	if len(body) > max {
+		body, truncated = body[:max], true
+	}
+	return sensitiveJSONRX.ReplaceAllString(
+		string(body), `$1"`+redacted+`"`), truncated
+}
 
-	buf, err := httputil.DumpResponse(res, !isBinOctetBody(res.Header))
-	if err != nil {
+// isLoginPath reports whether path is the login action, whose response
+// body is the session token.
+func isLoginPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/api/login")
+}
+
+func isBinOctetBody(h http.Header) bool {
+	return h.Get(HeaderKeyContentType) == headerValContentTypeBinaryOctetStream
+}
+
+// maxLogBodySize returns the number of body bytes that are logged, or a
+// negative number if bodies are not logged.
+func (c *client) maxLogBodySize() int {
+	if c.maxLogBody == 0 {
+		return DefaultMaxLogBodySize
+	}
+	return c.maxLogBody
+}
+
+// addBody sets the body field of a log entry to the redacted and
+// truncated body.
+func (c *client) addBody(fields Fields, h http.Header, body []byte) {
+	max := c.maxLogBodySize()
+	if max < 0 || len(body) == 0 {
+		return
+	}
+	if isBinOctetBody(h) {
+		fields["body"] = fmt.Sprintf("<%d bytes of binary data>", len(body))
 		return
 	}
+	s, truncated := redactBody(body, max)
+	fields["body"] = s
+	if truncated {
+		fields["bodyTruncated"] = true
+	}
+}
 
-	bw := &bytes.Buffer{}
-	WriteIndented(bw, buf)
+func (c *client) logRequest(
+	ctx context.Context,
+	req *http.Request,
+	payload []byte,
+	endpoint string,
+	attempt int) {
 
-	scanner := bufio.NewScanner(bw)
-	for {
-		if !scanner.Scan() {
-			break
+	fields := Fields{
+		"method":   req.Method,
+		"path":     req.URL.Path,
+		"endpoint": endpoint,
+		"attempt":  attempt,
+		"headers":  redactHeaders(req.Header),
+	}
+	c.addBody(fields, req.Header, payload)
+
+	c.logger.Log(ctx, LogDebug, "goscaleio http request", fields)
+}
+
+// logResponse logs res. As much of the body as is logged is read ahead
+// and put back in front of the rest, so res.Body can still be read in
+// full and closing it still closes the original body.
+func (c *client) logResponse(
+	ctx context.Context,
+	res *http.Response,
+	endpoint string,
+	attempt int,
+	d time.Duration) {
+
+	fields := Fields{
+		"method":   res.Request.Method,
+		"path":     res.Request.URL.Path,
+		"endpoint": endpoint,
+		"attempt":  attempt,
+		"status":   res.StatusCode,
+		"duration": d,
+		"headers":  redactHeaders(res.Header),
+	}
+
+	if max := c.maxLogBodySize(); max >= 0 {
+		buf, _ := ioutil.ReadAll(io.LimitReader(res.Body, int64(max)+1))
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), res.Body), res.Body}
+
+		if isLoginPath(res.Request.URL.Path) && len(buf) > 0 {
+			fields["body"] = redacted
+		} else {
+			c.addBody(fields, res.Header, buf)
 		}
-		fmt.Fprintln(w, scanner.Text())
 	}
 
-	log.Debug(w.String())
+	c.logger.Log(ctx, LogDebug, "goscaleio http response", fields)
 }
 
 // WriteIndentedN indents all lines n spaces.
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Basic OjAxMjM0NTY3ODk=")
+	h.Set("X-Auth-Token", "0123456789")
+	h.Set("Accept", "application/json")
+
+	m := redactHeaders(h)
+	if m["Authorization"] != redacted || m["X-Auth-Token"] != redacted {
+		t.Fatalf("Credentials not redacted %v", m)
+	}
+	if m["Accept"] != "application/json" {
+		t.Fatalf("Unexpected headers %v", m)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := map[string]string{
+		`{"oldPassword":"secret1","newPassword":"se\"cret2"}`: `{"oldPassword":"[REDACTED]","newPassword":"[REDACTED]"}`,
+		`{"name":"vol1","sizeInKb":"8388608"}`:                `{"name":"vol1","sizeInKb":"8388608"}`,
+		`{"mdmPassword" : "secret1"}`:                         `{"mdmPassword" : "[REDACTED]"}`,
+	}
+	for body, want := range tests {
+		if got, _ := redactBody([]byte(body), 1024); got != want {
+			t.Errorf("redactBody(%q) = %q, want %q", body, got, want)
+		}
+	}
+
+	// truncation must not leave part of a credential behind
+	got, truncated := redactBody([]byte(`{"password":"secret1"}`), 16)
+	if !truncated || strings.Contains(got, "sec") {
+		t.Fatalf("Unexpected truncated body %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the client's REST traffic. It is small
+// enough to be implemented on top of any metrics library; MetricsRecorder
+// is an implementation that keeps the measurements in memory, from which
+// e.g. a prometheus.Collector can be built.
+type Metrics interface {
+	// ObserveRequest is called after every request sent to the gateway.
+	// The path is templated, e.g. /api/instances/Volume::{id}, and the
+	// status code is zero if no response was received.
+	ObserveRequest(method, path string, statusCode int, d time.Duration)
+
+	// IncRetry is called every time a request is about to be retried.
+	IncRetry(method, path string)
+
+	// IncReauth is called every time the client logs in again because the
+	// gateway rejected its token.
+	IncReauth()
+}
+
+var idRX = regexp.MustCompile(`::[^/]+`)
+
+// TemplatePath replaces the object IDs in path with {id} so that
+// requests for different objects of the same type share a label, e.g.
+// /api/instances/Volume::1234/action/addMappedSdc becomes
+// /api/instances/Volume::{id}/action/addMappedSdc.
+func TemplatePath(path string) string {
+	return idRX.ReplaceAllString(path, "::{id}")
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram kept by a MetricsRecorder created without buckets.
+var DefaultLatencyBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// RequestKey identifies a series of requests in a MetricsSnapshot.
+type RequestKey struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// RequestStats is the latency histogram of a series of requests.
+type RequestStats struct {
+	// Count is the number of requests.
+	Count uint64
+
+	// Sum is the total latency of the requests in seconds.
+	Sum float64
+
+	// Buckets maps the upper bound of every bucket, in seconds, to the
+	// cumulative number of requests that took at most that long.
+	Buckets map[float64]uint64
+}
+
+// RouteKey identifies the requests to an endpoint regardless of their
+// outcome.
+type RouteKey struct {
+	Method string
+	Path   string
+}
+
+// MetricsSnapshot is a copy of the measurements of a MetricsRecorder.
+type MetricsSnapshot struct {
+	Requests map[RequestKey]RequestStats
+	Retries  map[RouteKey]uint64
+	Reauths  uint64
+}
+
+// MetricsRecorder is a Metrics implementation that keeps request counts,
+// latency histograms and retry and re-login counts in memory. It is safe
+// for concurrent use.
+type MetricsRecorder struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	requests map[RequestKey]*requestHistogram
+	retries  map[RouteKey]uint64
+	reauths  uint64
+}
+
+type requestHistogram struct {
+	count  uint64
+	sum    float64
+	counts []uint64
+}
+
+// NewMetricsRecorder returns a MetricsRecorder whose latency histograms
+// use the given bucket upper bounds in seconds, or DefaultLatencyBuckets
+// if none are given.
+func NewMetricsRecorder(buckets ...float64) *MetricsRecorder {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &MetricsRecorder{
+		buckets:  buckets,
+		requests: map[RequestKey]*requestHistogram{},
+		retries:  map[RouteKey]uint64{},
+	}
+}
+
+// ObserveRequest implements Metrics.
+func (m *MetricsRecorder) ObserveRequest(
+	method, path string, statusCode int, d time.Duration) {
+
+	key := RequestKey{Method: method, Path: path, StatusCode: statusCode}
+	secs := d.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.requests[key]
+	if !ok {
+		h = &requestHistogram{counts: make([]uint64, len(m.buckets))}
+		m.requests[key] = h
+	}
+	h.count++
+	h.sum += secs
+	for i, b := range m.buckets {
+		if secs <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+// IncRetry implements Metrics.
+func (m *MetricsRecorder) IncRetry(method, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[RouteKey{Method: method, Path: path}]++
+}
+
+// IncReauth implements Metrics.
+func (m *MetricsRecorder) IncReauth() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reauths++
+}
+
+// Snapshot returns a copy of the measurements recorded so far.
+func (m *MetricsRecorder) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MetricsSnapshot{
+		Requests: make(map[RequestKey]RequestStats, len(m.requests)),
+		Retries:  make(map[RouteKey]uint64, len(m.retries)),
+		Reauths:  m.reauths,
+	}
+	for k, h := range m.requests {
+		stats := RequestStats{
+			Count:   h.count,
+			Sum:     h.sum,
+			Buckets: make(map[float64]uint64, len(m.buckets)),
+		}
+		for i, b := range m.buckets {
+			stats.Buckets[b] = h.counts[i]
+		}
+		s.Requests[k] = stats
+	}
+	for k, n := range m.retries {
+		s.Retries[k] = n
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTemplatePath(t *testing.T) {
+	tests := map[string]string{
+		"/api/instances/Volume::1234/action/addMappedSdc": "/api/instances/Volume::{id}/action/addMappedSdc",
+		"/api/instances/System::abcd/relationships/Sdc":   "/api/instances/System::{id}/relationships/Sdc",
+		"/api/types/Volume/instances":                     "/api/types/Volume/instances",
+		"/api/instances/Volume::5678":                     "/api/instances/Volume::{id}",
+	}
+	for path, want := range tests {
+		if got := TemplatePath(path); got != want {
+			t.Errorf("TemplatePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	m := NewMetricsRecorder(0.1, 1)
+	m.ObserveRequest("GET", "/api/version", 200, 50*time.Millisecond)
+	m.ObserveRequest("GET", "/api/version", 200, 500*time.Millisecond)
+	m.ObserveRequest("GET", "/api/version", 200, 5*time.Second)
+	m.IncRetry("GET", "/api/version")
+	m.IncReauth()
+
+	s := m.Snapshot()
+	stats := s.Requests[RequestKey{"GET", "/api/version", 200}]
+	if stats.Count != 3 || stats.Buckets[0.1] != 1 || stats.Buckets[1] != 2 {
+		t.Fatalf("Unexpected histogram %+v", stats)
+	}
+	if s.Retries[RouteKey{"GET", "/api/version"}] != 1 || s.Reauths != 1 {
+		t.Fatalf("Unexpected counters %+v", s)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RoundTripperFunc adapts an ordinary function to an http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the RoundTripper that sends requests to the gateway,
+// e.g. to add headers, enforce a circuit breaker or record metrics.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RequestInfo describes a request the client sent to the gateway.
+type RequestInfo struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Path is the path of the request, e.g.
+	// /api/instances/Volume::1234/action/addMappedSdc.
+	Path string
+
+	// Endpoint is the gateway the request was sent to.
+	Endpoint string
+
+	// StatusCode is the HTTP status code of the response, or zero if no
+	// response was received.
+	StatusCode int
+
+	// Duration is the time it took to receive the response headers.
+	Duration time.Duration
+
+	// Attempt is the number of the attempt, starting at 1, when the
+	// request was retried or failed over to another gateway.
+	Attempt int
+
+	// Err is the error that prevented a response from being received.
+	Err error
+}
+
+// RequestHook is called after every request the client sends, including
+// every retry.
+type RequestHook func(ctx context.Context, info RequestInfo)
+
+// chainMiddleware wraps base in middleware so that middleware[0] is the
+// first to see a request.
+func chainMiddleware(
+	base http.RoundTripper, middleware []Middleware) http.RoundTripper {
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rt := base
+	for i := len(middleware) - 1; i >= 0; i-- {
+		rt = middleware[i](rt)
+	}
+	return rt
+}
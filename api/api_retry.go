@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+// RetryPolicy describes how the client retries requests that fail with a
+// transient error such as a connection reset or a 503 from the gateway.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after every attempt.
+	// Values less than 1 are treated as 1.
+	Multiplier float64
+
+	// Jitter is the fraction, between 0 and 1, of every delay that is
+	// randomized to avoid many clients retrying in lockstep.
+	Jitter float64
+
+	// RetryableStatusCodes are the HTTP status codes that are retried.
+	RetryableStatusCodes []int
+
+	// RetryableErrorCodes are the ScaleIO error codes, as returned in
+	// types.Error.ErrorCode, that are retried regardless of the HTTP
+	// status code they were returned with. Like RetryableStatusCodes they
+	// only apply to idempotent requests, or to every request if
+	// RetryNonIdempotent is set.
+	RetryableErrorCodes []int
+
+	// RetryNonIdempotent allows requests that are not idempotent, such as
+	// a POST that creates a volume, to be sent again after they may have
+	// reached the gateway. Requests that failed while dialing the gateway
+	// are always safe to retry.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy that retries up to four times
+// on connection errors and on 502, 503 and 504 responses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent returns a context that marks the request it is used for
+// as idempotent, allowing it to be retried even if its method, usually
+// POST, is not. It is meant for the gateway's query actions.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	v, _ := ctx.Value(idempotentKey{}).(bool)
+	return v
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delta := math.Min(p.Jitter, 1) * d
+		d = d - delta + rand.Float64()*2*delta
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryableErrorCode(code int) bool {
+	for _, c := range p.RetryableErrorCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry reports whether a request that produced res or err should
+// be sent again. If the decision requires looking at the error payload
+// res.Body is replaced with an unread copy.
+func (p *RetryPolicy) shouldRetry(
+	ctx context.Context,
+	idempotent bool,
+	res *http.Response,
+	err error) bool {
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		if isDialError(err) {
+			return true
+		}
+		return idempotent || p.RetryNonIdempotent
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return false
+	}
+	if !idempotent && !p.RetryNonIdempotent {
+		return false
+	}
+	if p.retryableStatus(res.StatusCode) {
+		return true
+	}
+	if len(p.RetryableErrorCodes) == 0 {
+		return false
+	}
+
+	// keep the original body's Close so whatever wraps it still sees it
+	buf, rerr := ioutil.ReadAll(res.Body)
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(buf), res.Body}
+	if rerr != nil {
+		return false
+	}
+	jsonError := &types.Error{}
+	if json.Unmarshal(buf, jsonError) != nil {
+		return false
+	}
+	return p.retryableErrorCode(jsonError.ErrorCode)
+}
+
+// isDialError reports whether err happened while connecting to the
+// gateway, in which case the request was never sent.
+func isDialError(err error) bool {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	if oe, ok := err.(*net.OpError); ok {
+		return oe.Op == "dial"
+	}
+	return false
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// drainBody discards what is left of a response that will not be used so
+// the underlying connection can be reused.
+func drainBody(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	errCACert      = errors.New("no certificates found in CA bundle")
+	errClientCert  = errors.New("client certificate and key must both be set")
+	errPinnedCert  = errors.New("gateway certificate does not match any pinned fingerprint")
+	errFingerprint = errors.New("invalid certificate fingerprint")
+)
+
+// TLSOptions configure how the client verifies the gateway and how it
+// authenticates itself to the gateway.
+type TLSOptions struct {
+	// CACertFile is the path of a PEM bundle of CA certificates trusted to
+	// sign the gateway's certificate.
+	CACertFile string
+
+	// CACert is a PEM bundle of CA certificates, used in addition to
+	// CACertFile.
+	CACert []byte
+
+	// ClientCertFile and ClientKeyFile are the paths of a PEM certificate
+	// and key presented to the gateway for mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// ClientCert and ClientKey are a PEM certificate and key presented to
+	// the gateway for mutual TLS. They take precedence over the files.
+	ClientCert []byte
+	ClientKey  []byte
+
+	// PinnedFingerprints are hex encoded SHA-256 fingerprints of the DER
+	// encoded gateway certificate. If any are set the gateway's leaf
+	// certificate must match one of them. Colons are ignored.
+	PinnedFingerprints []string
+
+	// ServerName is the name the gateway's certificate is verified
+	// against, if it differs from the host in the endpoint.
+	ServerName string
+}
+
+// newTransport returns the transport described by opts. It is a copy of
+// http.DefaultTransport, so proxies from HTTPS_PROXY and friends are used.
+func newTransport(opts ClientOptions) (*http.Transport, error) {
+
+	config := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+	}
+
+	if opts.UseCerts {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errSysCerts
+		}
+		config.RootCAs = pool
+	}
+
+	if t := opts.TLS; t != nil {
+		if err := t.apply(config); err != nil {
+			return nil, err
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	return transport, nil
+}
+
+func (t *TLSOptions) apply(config *tls.Config) error {
+
+	if t.CACertFile != "" || len(t.CACert) > 0 {
+		pem := append([]byte{}, t.CACert...)
+		if t.CACertFile != "" {
+			buf, err := ioutil.ReadFile(t.CACertFile)
+			if err != nil {
+				return err
+			}
+			pem = append(pem, '\n')
+			pem = append(pem, buf...)
+		}
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return errCACert
+		}
+	}
+
+	cert, key := t.ClientCert, t.ClientKey
+	if len(cert) == 0 && len(key) == 0 &&
+		(t.ClientCertFile != "" || t.ClientKeyFile != "") {
+		if t.ClientCertFile == "" || t.ClientKeyFile == "" {
+			return errClientCert
+		}
+		var err error
+		if cert, err = ioutil.ReadFile(t.ClientCertFile); err != nil {
+			return err
+		}
+		if key, err = ioutil.ReadFile(t.ClientKeyFile); err != nil {
+			return err
+		}
+	}
+	if len(cert) > 0 || len(key) > 0 {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	if t.ServerName != "" {
+		config.ServerName = t.ServerName
+	}
+
+	if len(t.PinnedFingerprints) > 0 {
+		pins := make(map[string]bool, len(t.PinnedFingerprints))
+		for _, fp := range t.PinnedFingerprints {
+			fp = strings.ToLower(strings.Replace(fp, ":", "", -1))
+			if b, err := hex.DecodeString(fp); err != nil ||
+				len(b) != sha256.Size {
+				return errFingerprint
+			}
+			pins[fp] = true
+		}
+		config.VerifyPeerCertificate = func(
+			rawCerts [][]byte, _ [][]*x509.Certificate) error {
+
+			if len(rawCerts) == 0 {
+				return errPinnedCert
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !pins[hex.EncodeToString(sum[:])] {
+				return errPinnedCert
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
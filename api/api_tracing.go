@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Tracer starts spans for the requests the client sends. It is small
+// enough to be implemented on top of OpenTelemetry or any other tracing
+// library.
+type Tracer interface {
+	// Start starts a span named name as a child of the span in ctx, if
+	// any, and returns a context that carries the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute on the span.
+	SetAttribute(key string, value interface{})
+
+	// RecordError records err as the outcome of the span.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// Attribute keys set on the spans started for every request.
+const (
+	AttrHTTPMethod     = "http.method"
+	AttrHTTPRoute      = "http.route"
+	AttrHTTPStatusCode = "http.status_code"
+	AttrEndpoint       = "goscaleio.endpoint"
+	AttrAttempt        = "goscaleio.attempt"
+	AttrErrorCode      = "goscaleio.error_code"
+)
+
+type spanKey struct{}
+
+// SpanFromContext returns the span of the request ctx belongs to, or nil.
+// It lets middleware annotate the span the client started.
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// startSpan starts the span for one attempt of a request if the client
+// has a Tracer.
+func (c *client) startSpan(
+	ctx context.Context,
+	method, path, endpoint string,
+	attempt int) (context.Context, Span) {
+
+	if c.tracer == nil {
+		return ctx, nil
+	}
+
+	route := TemplatePath(path)
+	ctx, span := c.tracer.Start(ctx, method+" "+route)
+	span.SetAttribute(AttrHTTPMethod, method)
+	span.SetAttribute(AttrHTTPRoute, route)
+	span.SetAttribute(AttrEndpoint, endpoint)
+	span.SetAttribute(AttrAttempt, attempt)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// finishSpan records the outcome of a request on span. If a response was
+// received the span is ended when its body is closed, so that errors
+// parsed from the body can still be recorded.
+func finishSpan(span Span, res *http.Response, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return
+	}
+	span.SetAttribute(AttrHTTPStatusCode, res.StatusCode)
+	res.Body = &spanBody{ReadCloser: res.Body, span: span}
+}
+
+type spanBody struct {
+	io.ReadCloser
+	span Span
+	once sync.Once
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.span.End)
+	return err
+}
@@ -1,10 +1,28 @@
 package goscaleio
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/thecodeteam/goscaleio/api"
+	types "github.com/thecodeteam/goscaleio/types/v1"
 )
 
 func setupClient(t *testing.T, hostAddr string) *Client {
@@ -60,7 +78,7 @@ func TestClientVersion(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	ver, err := client.getVersion()
+	ver, err := client.getVersion(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -137,3 +155,1753 @@ func TestClientLogin(t *testing.T) {
 		t.Fatal("Expecting an error for bad Login, but did not")
 	}
 }
+
+func TestClientContextCanceled(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/version":
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`"2.0"`))
+			case "/api/login":
+				handleAuthToken(resp, req)
+			default:
+				select {
+				case <-req.Context().Done():
+				case <-done:
+				}
+			}
+		},
+	))
+	defer server.Close()
+	client := setupClient(t, server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.GetVolumeCtx(ctx, "", "1234", "", "", false)
+	if err == nil {
+		t.Fatal("Expecting an error for canceled context, but did not")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatal("Expecting context deadline to be exceeded, got", ctx.Err())
+	}
+}
+
+func TestClientVersionHeadersPerClient(t *testing.T) {
+	newServer := func(version string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(
+			func(resp http.ResponseWriter, req *http.Request) {
+				switch req.RequestURI {
+				case "/api/version":
+					resp.WriteHeader(http.StatusOK)
+					resp.Write([]byte(`"` + version + `"`))
+				case "/api/login":
+					handleAuthToken(resp, req)
+				default:
+					if !requestAuthOK(resp, req) {
+						return
+					}
+					want := "application/json;version=" + version
+					if accept := req.Header.Get("Accept"); accept != want {
+						t.Errorf("Expecting Accept header %q, got %q",
+							want, accept)
+					}
+					resp.WriteHeader(http.StatusOK)
+					resp.Write([]byte(`[]`))
+				}
+			},
+		))
+	}
+	server20 := newServer("2.0")
+	defer server20.Close()
+	server25 := newServer("2.5")
+	defer server25.Close()
+
+	clients := []*Client{}
+	for _, server := range []*httptest.Server{server20, server25} {
+		client, err := NewClientWithArgs(server.URL+"/api", "", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.Authenticate(&ConfigConnect{
+			Username: "ScaleIOUser",
+			Password: "password",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, client := range clients {
+			wg.Add(1)
+			go func(client *Client) {
+				defer wg.Done()
+				if _, err := client.GetStoragePool(""); err != nil {
+					t.Error(err)
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	var gets, posts int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/login":
+				handleAuthToken(resp, req)
+			case "/api/types/StoragePool/instances":
+				if atomic.AddInt32(&gets, 1) < 3 {
+					resp.WriteHeader(http.StatusServiceUnavailable)
+					resp.Write([]byte(`{"message":"Unavailable","httpStatusCode":503,"errorCode":0}`))
+					return
+				}
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`[{"id":"pool1","name":"pool1"}]`))
+			case "/api/types/Volume/instances":
+				atomic.AddInt32(&posts, 1)
+				resp.WriteHeader(http.StatusServiceUnavailable)
+				resp.Write([]byte(`{"message":"Unavailable","httpStatusCode":503,"errorCode":0}`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	policy := api.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+		api.ClientOptions{RetryPolicy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := client.GetStoragePool("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&gets); len(pools) != 1 || n != 3 {
+		t.Fatalf("Expecting 1 pool after 3 attempts, got %d after %d",
+			len(pools), n)
+	}
+
+	_, err = client.CreateVolume(&types.VolumeParam{Name: "vol"}, "pool1")
+	if err == nil {
+		t.Fatal("Expecting an error creating volume, but did not")
+	}
+	if n := atomic.LoadInt32(&posts); n != 1 {
+		t.Fatal("Expecting non-idempotent POST to be sent once, got", n)
+	}
+}
+
+func TestClientRetryErrorCodes(t *testing.T) {
+	var queries, posts int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/types/Volume/instances/action/queryIdByKey":
+				atomic.AddInt32(&queries, 1)
+			case "/api/instances/Volume::vol1/action/addMappedSdc":
+				atomic.AddInt32(&posts, 1)
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusInternalServerError)
+			resp.Write([]byte(`{"message":"Busy","httpStatusCode":500,"errorCode":65}`))
+		},
+	))
+	defer server.Close()
+
+	for _, retryNonIdempotent := range []bool{false, true} {
+		atomic.StoreInt32(&queries, 0)
+		atomic.StoreInt32(&posts, 0)
+
+		policy := api.DefaultRetryPolicy()
+		policy.InitialBackoff = time.Millisecond
+		policy.RetryableErrorCodes = []int{65}
+		policy.RetryNonIdempotent = retryNonIdempotent
+		client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+			api.ClientOptions{RetryPolicy: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.FindVolumeID("vol1"); err == nil {
+			t.Fatal("Expecting an error finding volume, but did not")
+		}
+		if n := atomic.LoadInt32(&queries); n != 4 {
+			t.Fatal("Expecting idempotent query to be sent 4 times, got", n)
+		}
+
+		vol := NewVolume(client)
+		vol.Volume.ID = "vol1"
+		err = vol.MapVolumeSdc(&types.MapVolumeSdcParam{SdcID: "sdc1"})
+		if err == nil {
+			t.Fatal("Expecting an error mapping volume, but did not")
+		}
+		want := int32(1)
+		if retryNonIdempotent {
+			want = 4
+		}
+		if n := atomic.LoadInt32(&posts); n != want {
+			t.Fatalf("Expecting POST to be sent %d times with "+
+				"RetryNonIdempotent %v, got %d", want, retryNonIdempotent, n)
+		}
+	}
+}
+
+func TestClientReauthReplaysRequest(t *testing.T) {
+	var logins, expired int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/login":
+				n := atomic.AddInt32(&logins, 1)
+				resp.WriteHeader(http.StatusOK)
+				fmt.Fprintf(resp, `"token%d"`, n)
+			case "/api/instances/Volume::1234/action/addMappedSdc":
+				// the first token expires after its first use
+				if _, pwd, _ := req.BasicAuth(); pwd == "token1" {
+					atomic.AddInt32(&expired, 1)
+					resp.WriteHeader(http.StatusUnauthorized)
+					resp.Write([]byte(`{"message":"Unauthorized","httpStatusCode":401,"errorCode":0}`))
+					return
+				}
+				if ct := req.Header.Get("Content-Type"); ct != "application/json;version=2.0" {
+					t.Errorf("Expecting versioned Content-Type, got %q", ct)
+				}
+				param := &types.MapVolumeSdcParam{}
+				if err := json.NewDecoder(req.Body).Decode(param); err != nil {
+					t.Error(err)
+				}
+				if param.SdcID != "sdc1" {
+					t.Errorf("Expecting sdcId sdc1 in replayed body, got %q",
+						param.SdcID)
+				}
+				resp.WriteHeader(http.StatusOK)
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "1234"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := vol.MapVolumeSdc(&types.MapVolumeSdcParam{SdcID: "sdc1"})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&logins); n != 2 {
+		t.Fatalf("Expecting a single re-login for %d rejected requests, got %d",
+			atomic.LoadInt32(&expired), n-1)
+	}
+}
+
+func TestClientBadCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(handleAuthToken))
+	defer server.Close()
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{Username: "ScaleIOUser"})
+	if !errors.Is(err, ErrAuthentication) {
+		t.Fatal("Expecting ErrAuthentication, got", err)
+	}
+	var apiErr *types.Error
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != 401 {
+		t.Fatal("Expecting wrapped 401 *types.Error, got", err)
+	}
+}
+
+func TestClientEndpointFailover(t *testing.T) {
+	var logins int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/login":
+				atomic.AddInt32(&logins, 1)
+				handleAuthToken(resp, req)
+			case "/api/types/StoragePool/instances":
+				if !requestAuthOK(resp, req) {
+					return
+				}
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`[]`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	// a gateway that is down
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	var changes []string
+	client, err := NewClientWithOptions(down.URL+"/api", "2.0",
+		api.ClientOptions{
+			Endpoints: []string{server.URL + "/api"},
+			OnEndpointChange: func(from, to string) {
+				changes = append(changes, to)
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Endpoint() != server.URL {
+		t.Fatal("Expecting client to fail over to", server.URL,
+			"got", client.Endpoint())
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetStoragePool(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&logins); len(changes) != 1 || n != 1 {
+		t.Fatalf("Expecting to stick to the healthy endpoint, got %d "+
+			"changes and %d logins", len(changes), n)
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("X-Request-Id") != "req-1" {
+				t.Error("Expecting X-Request-Id header from middleware")
+			}
+			switch req.RequestURI {
+			case "/api/login":
+				handleAuthToken(resp, req)
+			case "/api/types/StoragePool/instances":
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`[]`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	var (
+		order []string
+		infos []api.RequestInfo
+	)
+	tag := func(name string) api.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return api.RoundTripperFunc(
+				func(req *http.Request) (*http.Response, error) {
+					order = append(order, name)
+					req.Header.Set("X-Request-Id", "req-1")
+					return next.RoundTrip(req)
+				})
+		}
+	}
+	client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+		api.ClientOptions{
+			Middleware: []api.Middleware{tag("outer"), tag("inner")},
+			OnRequest: func(ctx context.Context, info api.RequestInfo) {
+				infos = append(infos, info)
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetStoragePool(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order) != 4 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatal("Expecting middleware to run in order, got", order)
+	}
+	if len(infos) != 2 {
+		t.Fatal("Expecting 2 requests to be observed, got", len(infos))
+	}
+	info := infos[1]
+	if info.Method != http.MethodGet ||
+		info.Path != "/api/types/StoragePool/instances" ||
+		info.StatusCode != http.StatusOK || info.Endpoint != server.URL {
+		t.Fatalf("Unexpected request info %+v", info)
+	}
+}
+
+func TestClientTLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(handleAuthToken))
+	defer server.Close()
+
+	cert := server.Certificate()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	sum := sha256.Sum256(cert.Raw)
+
+	tests := []struct {
+		name string
+		tls  *api.TLSOptions
+		ok   bool
+	}{
+		{"untrusted", nil, false},
+		{"ca bundle", &api.TLSOptions{CACert: caPEM}, true},
+		{"pinned", &api.TLSOptions{
+			CACert:             caPEM,
+			PinnedFingerprints: []string{hex.EncodeToString(sum[:])},
+		}, true},
+		{"wrong pin", &api.TLSOptions{
+			CACert:             caPEM,
+			PinnedFingerprints: []string{strings.Repeat("00", sha256.Size)},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+			api.ClientOptions{TLS: tt.tls})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.Authenticate(&ConfigConnect{
+			Username: "ScaleIOUser",
+			Password: "password",
+		})
+		if tt.ok && err != nil {
+			t.Errorf("%s: expecting login to succeed, got %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: expecting login to fail, but did not", tt.name)
+		}
+	}
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+
+func (s *testSpan) End() { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(
+	ctx context.Context, name string) (context.Context, api.Span) {
+
+	span := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestClientTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/login":
+				handleAuthToken(resp, req)
+			case "/api/instances/Volume::1234/action/addMappedSdc":
+				resp.WriteHeader(http.StatusInternalServerError)
+				resp.Write([]byte(`{"message":"Failed","httpStatusCode":500,"errorCode":42}`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	tracer := &testTracer{}
+	client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+		api.ClientOptions{Tracer: tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "1234"
+	if err := vol.MapVolumeSdc(&types.MapVolumeSdcParam{}); err == nil {
+		t.Fatal("Expecting an error mapping volume, but did not")
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatal("Expecting 2 spans, got", len(tracer.spans))
+	}
+	span := tracer.spans[1]
+	if span.name != "POST /api/instances/Volume::{id}/action/addMappedSdc" {
+		t.Fatal("Unexpected span name", span.name)
+	}
+	if !span.ended || span.err == nil ||
+		span.attrs[api.AttrHTTPStatusCode] != 500 ||
+		span.attrs[api.AttrErrorCode] != 42 ||
+		span.attrs[api.AttrAttempt] != 1 {
+		t.Fatalf("Unexpected span %+v", span)
+	}
+}
+
+func TestClientShowHTTPRedacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/login":
+				handleAuthToken(resp, req)
+			case "/api/instances/User::1234/action/setPassword":
+				resp.WriteHeader(http.StatusOK)
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	var (
+		mu      sync.Mutex
+		entries []string
+	)
+	logger := api.LoggerFunc(func(
+		ctx context.Context, level api.LogLevel, msg string, fields api.Fields) {
+
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, fmt.Sprintf("%s %v", msg, fields))
+	})
+
+	client, err := NewClientWithOptions(server.URL+"/api", "2.0",
+		api.ClientOptions{ShowHTTP: true, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Authenticate(&ConfigConnect{
+		Username: "ScaleIOUser",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.getJSONWithRetry(context.Background(), http.MethodPost,
+		"/api/instances/User::1234/action/setPassword",
+		map[string]string{
+			"oldPassword": "oldSecret",
+			"newPassword": "newSecret",
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(strings.Join(entries, "\n"),
+		`"newPassword":"[REDACTED]"`) {
+		t.Fatal("Expecting a redacted request body, got", entries)
+	}
+	for _, e := range entries {
+		for _, secret := range []string{
+			"012345678901234567890123456789",
+			"oldSecret",
+			"newSecret",
+			basicAuth("ScaleIOUser", "password"),
+			basicAuth("", "012345678901234567890123456789"),
+		} {
+			if strings.Contains(e, secret) {
+				t.Fatalf("Log entry %q contains a credential", e)
+			}
+		}
+	}
+}
+
+func TestVolumeSetVolumeSize(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			if req.RequestURI != "/api/instances/Volume::1234/action/setVolumeSize" {
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			resp.WriteHeader(http.StatusOK)
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "1234"
+	vol.Volume.SizeInKb = 16 * 1024 * 1024
+
+	for _, size := range []int{0, 12, 8} {
+		if err := vol.SetVolumeSize(size); !errors.Is(err, ErrInvalidArgument) {
+			t.Fatalf("Expecting ErrInvalidArgument for %d GB, got %v", size, err)
+		}
+	}
+	if body != nil {
+		t.Fatal("Invalid size was sent to the gateway")
+	}
+
+	if err := vol.SetVolumeSize(24); err != nil {
+		t.Fatal(err)
+	}
+	if body["sizeInGB"] != "24" || vol.Volume.SizeInKb != 24*1024*1024 {
+		t.Fatalf("Unexpected request %v or size %d", body, vol.Volume.SizeInKb)
+	}
+}
+
+func TestVolumeSnapshots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			var body string
+			switch req.RequestURI {
+			case "/api/types/System/instances":
+				body = `[{"id":"sys1","links":[{"rel":"self","href":"/api/instances/System::sys1"}]}]`
+			case "/api/instances/System::sys1/action/snapshotVolumes":
+				var param types.SnapshotVolumesParam
+				json.NewDecoder(req.Body).Decode(&param)
+				if len(param.SnapshotDefs) != 1 ||
+					param.SnapshotDefs[0].VolumeID != "base" ||
+					param.SnapshotDefs[0].SnapshotName != "snap1" {
+					t.Fatalf("Unexpected snapshot request %+v", param)
+				}
+				body = `{"volumeIdList":["snap1"],"snapshotGroupId":"group1"}`
+			case "/api/instances/Volume::snap1":
+				body = `{"id":"snap1","ancestorVolumeId":"base","vtreeId":"vt1","links":[{"rel":"/api/parent/relationship/vtreeId","href":"/api/instances/VTree::vt1"}]}`
+			case "/api/instances/Volume::base":
+				body = `{"id":"base","vtreeId":"vt1"}`
+			case "/api/instances/VTree::vt1":
+				body = `{"id":"vt1","baseVolumeId":"base"}`
+			case "/api/instances/VTree::vt1/relationships/Volume":
+				body = `[{"id":"snap2","ancestorVolumeId":"snap1","creationTime":3},
+					{"id":"base","creationTime":1},
+					{"id":"snap3","ancestorVolumeId":"base","creationTime":4},
+					{"id":"snap1","ancestorVolumeId":"base","creationTime":2},
+					{"id":"snap4","ancestorVolumeId":"removed","creationTime":5}]`
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "base"
+	vol.Volume.VTreeID = "vt1"
+
+	snap, err := vol.CreateSnapshot("snap1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Volume.ID != "snap1" {
+		t.Fatal("Unexpected snapshot", snap.Volume.ID)
+	}
+
+	parent, err := snap.GetParent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Volume.ID != "base" {
+		t.Fatal("Unexpected parent", parent.Volume.ID)
+	}
+
+	snaps, err := vol.ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || snaps[0].ID != "snap3" || snaps[1].ID != "snap1" {
+		t.Fatalf("Unexpected snapshots %+v", snaps)
+	}
+
+	if _, err := NewVolume(client).ListSnapshots(); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+
+	tree, err := snap.GetSnapshotTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var walked []string
+	tree.Walk(func(v *types.Volume, depth int) error {
+		walked = append(walked, fmt.Sprintf("%s:%d", v.ID, depth))
+		return nil
+	})
+	if strings.Join(walked, ",") != "base:0,snap1:1,snap2:2,snap3:1,snap4:1" {
+		t.Fatal("Unexpected tree", walked)
+	}
+}
+
+func TestSystemSnapshotGroup(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		overwrites []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			var body string
+			switch req.RequestURI {
+			case "/api/instances/System::sys1/relationships/Volume":
+				body = `[{"id":"vol1"},{"id":"vol2"},
+					{"id":"snap1","ancestorVolumeId":"vol1","consistencyGroupId":"group1"},
+					{"id":"snap2","ancestorVolumeId":"vol2","consistencyGroupId":"group1"},
+					{"id":"snap3","ancestorVolumeId":"vol1","consistencyGroupId":"group2"},
+					{"id":"snap4","consistencyGroupId":"group1"},
+					{"id":"snap5","consistencyGroupId":"group4"},
+					{"id":"snap6","ancestorVolumeId":"vol1","consistencyGroupId":"group5"},
+					{"id":"snap7","ancestorVolumeId":"vol5","consistencyGroupId":"group5"}]`
+			case "/api/instances/Volume::vol5/action/overwriteVolumeContent":
+				resp.WriteHeader(http.StatusInternalServerError)
+				resp.Write([]byte(`{"message":"Internal error","httpStatusCode":500,"errorCode":0}`))
+				return
+			case "/api/instances/Volume::vol1/action/overwriteVolumeContent",
+				"/api/instances/Volume::vol2/action/overwriteVolumeContent":
+				var param types.OverwriteVolumeContentParam
+				json.NewDecoder(req.Body).Decode(&param)
+				mu.Lock()
+				overwrites = append(overwrites,
+					strings.Split(req.RequestURI, "::")[1][:4]+"<"+param.SrcVolumeID)
+				mu.Unlock()
+			case "/api/instances/System::sys1/action/removeConsistencyGroupSnapshots":
+				var param types.RemoveConsistencyGroupSnapshotsParam
+				json.NewDecoder(req.Body).Decode(&param)
+				if param.SnapGroupID != "group1" {
+					t.Fatal("Unexpected group", param.SnapGroupID)
+				}
+				body = `{"numberOfVolumes":2}`
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	system := NewSystem(client)
+	system.System.ID = "sys1"
+	system.System.Links = []*types.Link{
+		{Rel: "self", HREF: "/api/instances/System::sys1"}}
+
+	snaps, err := system.GetSnapshotGroupVolumes("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("Unexpected snapshots %+v", snaps)
+	}
+
+	if _, err := system.GetSnapshotGroupVolumes("group3"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+
+	err = system.RestoreSnapshotGroup("group1", "vol2", "vol3")
+	if !errors.Is(err, ErrNotFound) || len(overwrites) != 0 {
+		t.Fatal("Expecting ErrNotFound and no restore, got", err, overwrites)
+	}
+
+	if err := system.RestoreSnapshotGroup("group1", "vol2"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(overwrites, ",") != "vol2<snap2" {
+		t.Fatal("Unexpected restore", overwrites)
+	}
+
+	// snapshots whose source volume was removed are left out
+	if err := system.RestoreSnapshotGroup("group4"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+	overwrites = nil
+	if err := system.RestoreSnapshotGroup("group1"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(overwrites, ",") != "vol1<snap1,vol2<snap2" {
+		t.Fatal("Unexpected restore", overwrites)
+	}
+
+	err = system.RestoreSnapshotGroup("group5")
+	if err == nil || !strings.Contains(err.Error(), "after restoring [vol1]") {
+		t.Fatal("Expecting the restored volumes to be named, got", err)
+	}
+
+	removeResp, err := system.RemoveConsistencyGroupSnapshots("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removeResp.NumberOfVolumes != 2 {
+		t.Fatal("Unexpected response", removeResp)
+	}
+}
+
+func TestVolumeOverwriteFrom(t *testing.T) {
+	var overwrites int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			var body string
+			switch req.RequestURI {
+			case "/api/instances/Volume::vol1":
+				body = `{"id":"vol1","vtreeId":"vt1","mappedSdcInfo":[{"sdcId":"sdc1","sdcIp":"10.0.0.1"}]}`
+			case "/api/instances/Volume::snap1":
+				body = `{"id":"snap1","vtreeId":"vt1","ancestorVolumeId":"vol1"}`
+			case "/api/instances/Volume::snap2":
+				body = `{"id":"snap2","vtreeId":"vt2","ancestorVolumeId":"vol2"}`
+			case "/api/instances/Volume::vol1/action/overwriteVolumeContent":
+				var param types.OverwriteVolumeContentParam
+				json.NewDecoder(req.Body).Decode(&param)
+				if param.SrcVolumeID != "snap1" {
+					t.Fatal("Unexpected source", param.SrcVolumeID)
+				}
+				atomic.AddInt32(&overwrites, 1)
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "vol1"
+
+	report, err := vol.OverwriteFromDryRun("snap1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.VTreeID != "vt1" || len(report.MappedSdcs) != 1 ||
+		report.MappedSdcs[0].SdcID != "sdc1" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if vol.Volume.VTreeID != "" || vol.Volume.MappedSdcInfo != nil {
+		t.Fatalf("Dry run changed the volume %+v", vol.Volume)
+	}
+
+	if err := vol.OverwriteFrom("snap2"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if atomic.LoadInt32(&overwrites) != 0 {
+		t.Fatal("Volume was overwritten by dry run or from another VTree")
+	}
+
+	if err := vol.OverwriteFrom("snap1"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&overwrites) != 1 {
+		t.Fatal("Volume was not overwritten")
+	}
+}
+
+func TestClientGetVolumesByIDs(t *testing.T) {
+	var queries, gets int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.RequestURI == "/api/types/Volume/instances/action/queryBySelectedIds":
+				atomic.AddInt32(&queries, 1)
+				var param types.VolumeQeryBySelectedIdsParam
+				json.NewDecoder(req.Body).Decode(&param)
+				var volumes []*types.Volume
+				for _, id := range param.IDs {
+					if id == "missing" {
+						resp.WriteHeader(http.StatusInternalServerError)
+						resp.Write([]byte(`{"message":"Could not find the volume","httpStatusCode":500,"errorCode":0}`))
+						return
+					}
+					volumes = append(volumes, &types.Volume{ID: id})
+				}
+				json.NewEncoder(resp).Encode(volumes)
+			case strings.HasPrefix(req.RequestURI, "/api/instances/Volume::"):
+				atomic.AddInt32(&gets, 1)
+				id := strings.TrimPrefix(req.RequestURI, "/api/instances/Volume::")
+				if id == "missing" {
+					resp.WriteHeader(http.StatusInternalServerError)
+					resp.Write([]byte(`{"message":"Could not find the volume","httpStatusCode":500,"errorCode":0}`))
+					return
+				}
+				json.NewEncoder(resp).Encode(&types.Volume{ID: id})
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 0; i < volumeQueryChunkSize+2; i++ {
+		ids = append(ids, fmt.Sprintf("vol%d", i))
+	}
+	ids = append(ids, "missing", "vol0")
+
+	volumes, missing, err := client.GetVolumesByIDs(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != volumeQueryChunkSize+2 ||
+		volumes["vol7"] == nil || volumes["vol7"].ID != "vol7" {
+		t.Fatal("Unexpected volumes", len(volumes))
+	}
+	if len(missing) != 1 || missing[0] != "missing" {
+		t.Fatal("Unexpected missing volumes", missing)
+	}
+	if atomic.LoadInt32(&queries) != 2 || atomic.LoadInt32(&gets) != 3 {
+		t.Fatal("Unexpected requests", queries, gets)
+	}
+}
+
+func TestClientListVolumes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			var body string
+			switch req.RequestURI {
+			case "/api/instances/ProtectionDomain::pd1/relationships/StoragePool":
+				body = `[{"id":"sp1"}]`
+			case "/api/types/Volume/instances":
+				body = `[{"id":"vol1","name":"pvc-1","storagePoolId":"sp1","volumeType":"ThinProvisioned","sizeInKb":8388608},
+					{"id":"vol2","name":"pvc-2","storagePoolId":"sp1","volumeType":"ThickProvisioned","sizeInKb":16777216},
+					{"id":"vol3","name":"db-1","storagePoolId":"sp1","volumeType":"ThinProvisioned","sizeInKb":8388608},
+					{"id":"vol4","name":"pvc-3","storagePoolId":"sp2","volumeType":"ThinProvisioned","sizeInKb":8388608}]`
+			case "/api/instances/Sdc::sdc1/relationships/Volume":
+				body = `[{"id":"vol1","mappedSdcInfo":[{"sdcId":"sdc1"}]},
+					{"id":"vol2","mappedSdcInfo":[{"sdcId":"sdc1"}],"volumeType":"Snapshot"}]`
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(body))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(volumes []*Volume) string {
+		var s []string
+		for _, v := range volumes {
+			s = append(s, v.Volume.ID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	tests := []struct {
+		q    *VolumeQuery
+		want string
+	}{
+		{nil, "vol1,vol2,vol3,vol4"},
+		{&VolumeQuery{ProtectionDomainID: "pd1", NamePrefix: "pvc-"}, "vol1,vol2"},
+		{&VolumeQuery{VolumeType: VolumeTypeThin, MaxSizeInKb: 8388608,
+			NameRegexp: regexp.MustCompile(`-[13]$`)}, "vol1,vol3,vol4"},
+		{&VolumeQuery{MinSizeInKb: 16777216}, "vol2"},
+		{&VolumeQuery{SdcID: "sdc1", VolumeType: VolumeTypeSnapshot}, "vol2"},
+	}
+	for _, test := range tests {
+		volumes, err := client.ListVolumes(test.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(volumes); got != test.want {
+			t.Errorf("ListVolumes(%+v) = %s, want %s", test.q, got, test.want)
+		}
+	}
+}
+
+func TestVolumeGetStatistics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			if req.RequestURI != "/api/instances/Volume::1234/relationships/Statistics" {
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(`{
+				"userDataReadBwc":{"totalWeightInKb":4000,"numOccured":100,"numSeconds":5},
+				"userDataWriteBwc":{"totalWeightInKb":0,"numOccured":0,"numSeconds":0},
+				"numOfMappedSdcs":2,"mappedSdcIds":["sdc1","sdc2"]}`))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol := NewVolume(client)
+	vol.Volume.ID = "1234"
+
+	stats, err := vol.GetStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumOfMappedSdcs != 2 || len(stats.MappedSdcIds) != 2 {
+		t.Fatalf("Unexpected statistics %+v", stats)
+	}
+	if stats.ReadIOPS() != 20 || stats.ReadBandwidthKBps() != 800 ||
+		stats.WriteIOPS() != 0 || stats.WriteBandwidthKBps() != 0 {
+		t.Fatalf("Unexpected rates %v %v %v %v", stats.ReadIOPS(),
+			stats.ReadBandwidthKBps(), stats.WriteIOPS(),
+			stats.WriteBandwidthKBps())
+	}
+}
+
+func TestClientStatisticsQuery(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			if req.RequestURI != "/api/types/Volume/instances/action/querySelectedStatistics" {
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			atomic.AddInt32(&requests, 1)
+
+			var param map[string]interface{}
+			json.NewDecoder(req.Body).Decode(&param)
+			ids, _ := param["ids"].([]interface{})
+			if _, all := param["allIds"]; all {
+				ids = []interface{}{"vol1"}
+			}
+
+			stats := map[string]interface{}{}
+			for _, id := range ids {
+				stats[id.(string)] = map[string]interface{}{
+					"userDataReadBwc": map[string]int{
+						"totalWeightInKb": 100, "numOccured": 10, "numSeconds": 1},
+					"numOfMappedSdcs": 1,
+				}
+			}
+			json.NewEncoder(resp).Encode(stats)
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.NewStatisticsQuery("Volume").IDs("vol1").GetInto(
+		&map[string]*types.VolumeStatistics{})
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument without properties, got", err)
+	}
+
+	var ids []string
+	for i := 0; i < statisticsQueryChunkSize+1; i++ {
+		ids = append(ids, fmt.Sprintf("vol%d", i))
+	}
+	stats := map[string]*types.VolumeStatistics{}
+	err = client.NewStatisticsQuery("Volume").
+		IDs(ids...).
+		Properties("userDataReadBwc", "numOfMappedSdcs").
+		GetInto(&stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != len(ids) || atomic.LoadInt32(&requests) != 2 {
+		t.Fatal("Unexpected statistics", len(stats), requests)
+	}
+	if stats["vol7"].ReadIOPS() != 10 || stats["vol7"].NumOfMappedSdcs != 1 {
+		t.Fatalf("Unexpected statistics %+v", stats["vol7"])
+	}
+
+	records, err := client.NewStatisticsQuery("Volume").
+		AllIDs().
+		Properties("numOfMappedSdcs").
+		Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records["vol1"]["numOfMappedSdcs"] != float64(1) {
+		t.Fatal("Unexpected records", records)
+	}
+}
+
+func TestStoragePoolStatisticsSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			if req.RequestURI != "/api/instances/StoragePool::sp1/relationships/Statistics" {
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(`{
+				"totalReadBwc":{"totalWeightInKb":4096,"numOccured":512,"numSeconds":4},
+				"totalWriteBwc":{"totalWeightInKb":0,"numOccured":0,"numSeconds":0},
+				"fwdRebuildReadBwc":{"totalWeightInKb":100,"numOccured":1,"numSeconds":1},
+				"bckRebuildReadBwc":{"totalWeightInKb":50,"numOccured":1,"numSeconds":1},
+				"maxCapacityInKb":1000,"capacityInUseInKb":250,"spareCapacityInKb":100}`))
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sp := NewStoragePool(client)
+	sp.StoragePool.Links = []*types.Link{{
+		Rel:  "/api/StoragePool/relationship/Statistics",
+		HREF: "/api/instances/StoragePool::sp1/relationships/Statistics",
+	}}
+
+	stats, err := sp.GetStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalReadBwc.AvgIOSizeKb() != 8 {
+		t.Fatal("Unexpected average IO size", stats.TotalReadBwc.AvgIOSizeKb())
+	}
+
+	want := types.StatisticsSummary{
+		ReadIOPS:                 128,
+		ReadBandwidthKBps:        1024,
+		RebuildReadBandwidthKBps: 150,
+		CapacityInUsePercent:     25,
+		SpareCapacityPercent:     10,
+	}
+	if got := stats.Summary(); got != want {
+		t.Fatalf("Summary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSdcAdministration(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		actions []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/instances/System::sys1/relationships/Sdc":
+				resp.Write([]byte(`[{"id":"sdc1","sdcGuid":"GUID-1","sdcApproved":true},
+					{"id":"sdc2","sdcGuid":"GUID-2","sdcApproved":false}]`))
+				return
+			case "/api/instances/Sdc::sdc2":
+				resp.Write([]byte(`{"id":"sdc2","name":"host2","perfProfile":"Default"}`))
+				return
+			case "/api/instances/Sdc::sdc4":
+				resp.Write([]byte(`{"id":"sdc4","name":"renamed","perfProfile":"HighPerformance"}`))
+				return
+			case "/api/instances/Sdc::gone/action/removeSdc":
+				resp.WriteHeader(http.StatusInternalServerError)
+				resp.Write([]byte(`{"message":"Could not find the SDC","httpStatusCode":500,"errorCode":0}`))
+				return
+			}
+
+			body, _ := ioutil.ReadAll(req.Body)
+			mu.Lock()
+			actions = append(actions,
+				path.Base(req.RequestURI)+" "+string(bytes.TrimSpace(body)))
+			mu.Unlock()
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	system := NewSystem(client)
+	system.System.ID = "sys1"
+	if err := system.ApproveSdc("guid-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := system.ApproveSdc("GUID-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	sdc := NewSdc(client, &types.Sdc{ID: "sdc2", Name: "host2",
+		PerfProfile: SdcPerfProfileDefault})
+	if err := sdc.SetSdcName("host2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sdc.SetSdcName("host3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sdc.SetSdcPerformanceProfile("Fast"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if err := sdc.SetSdcPerformanceProfile(SdcPerfProfileDefault); err != nil {
+		t.Fatal(err)
+	}
+	if err := sdc.SetSdcPerformanceProfile(SdcPerfProfileHighPerformance); err != nil {
+		t.Fatal(err)
+	}
+	if err := sdc.RemoveSdc(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSdc(client, &types.Sdc{ID: "gone"}).RemoveSdc(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a wrapper that predates changes made elsewhere still applies them
+	stale := NewSdc(client, &types.Sdc{ID: "sdc4", Name: "host4",
+		PerfProfile: SdcPerfProfileCompact})
+	if err := stale.SetSdcName("host4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stale.SetSdcPerformanceProfile(SdcPerfProfileCompact); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`approveSdc {"sdcGuid":"GUID-2"}`,
+		`setSdcName {"sdcName":"host3"}`,
+		`setSdcPerformanceParameters {"perfProfile":"HighPerformance"}`,
+		`removeSdc {}`,
+		`setSdcName {"sdcName":"host4"}`,
+		`setSdcPerformanceParameters {"perfProfile":"Compact"}`,
+	}
+	if strings.Join(actions, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected actions %q", actions)
+	}
+}
+
+func TestSdcGetStatistics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/instances/Sdc::sdc1/relationships/Statistics":
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`{
+					"userDataReadBwc":{"totalWeightInKb":2048,"numOccured":256,"numSeconds":2},
+					"userDataWriteBwc":{"totalWeightInKb":1024,"numOccured":64,"numSeconds":2},
+					"numOfMappedVolumes":2,"volumeIds":["vol1","vol2"]}`))
+			case "/api/instances/Sdc::sdc2/relationships/Statistics":
+				resp.WriteHeader(http.StatusInternalServerError)
+				resp.Write([]byte(`{"message":"Could not find the SDC","httpStatusCode":500,"errorCode":0}`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sdc := NewSdc(client, &types.Sdc{
+		ID:                 "sdc1",
+		MdmConnectionState: "Connected",
+		Links: []*types.Link{{
+			Rel:  "/api/Sdc/relationship/Statistics",
+			HREF: "/api/instances/Sdc::sdc1/relationships/Statistics",
+		}},
+	})
+
+	stats, err := sdc.GetStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil {
+		t.Fatal("Expecting statistics, got nil")
+	}
+	if stats.NumOfMappedVolumes != 2 || len(stats.VolumeIds) != 2 ||
+		stats.MdmConnectionState != "Connected" {
+		t.Fatalf("Unexpected statistics %+v", stats)
+	}
+
+	summary := stats.Summary()
+	if summary.ReadIOPS != 128 || summary.WriteIOPS != 32 ||
+		summary.ReadBandwidthKBps != 1024 || summary.WriteBandwidthKBps != 512 {
+		t.Fatalf("Unexpected summary %+v", summary)
+	}
+
+	gone := NewSdc(client, &types.Sdc{
+		ID: "sdc2",
+		Links: []*types.Link{{
+			Rel:  "/api/Sdc/relationship/Statistics",
+			HREF: "/api/instances/Sdc::sdc2/relationships/Statistics",
+		}},
+	})
+	if stats, err := gone.GetStatistics(); !errors.Is(err, ErrNotFound) || stats != nil {
+		t.Fatal("Expecting ErrNotFound and no statistics, got", stats, err)
+	}
+}
+
+func TestFindLookups(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		listings int
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/instances/System::sys1/relationships/Sdc":
+				mu.Lock()
+				listings++
+				mu.Unlock()
+				resp.Write([]byte(`[
+					{"id":"sdc1","systemId":"sys1","sdcGuid":"GUID-1","SdcIp":"10.0.0.1"},
+					{"id":"sdc2","systemId":"sys1","sdcGuid":"GUID-2","SdcIp":"10.0.0.2"},
+					{"id":"sdc3","systemId":"sys1","sdcGuid":"GUID-3","SdcIp":"10.0.0.2"}]`))
+			case "/api/instances/Sdc::sdc1":
+				resp.Write([]byte(`{"id":"sdc1","systemId":"sys1","name":"host1"}`))
+			case "/api/types/Sdc/instances/action/queryIdByKey":
+				resp.Write([]byte(`"sdc1"`))
+			case "/api/instances/Sdc::other":
+				resp.Write([]byte(`{"id":"other","systemId":"sys2"}`))
+			case "/api/instances/ProtectionDomain::pd1/relationships/Sds":
+				resp.Write([]byte(`[
+					{"id":"sds1","protectionDomainId":"pd1","ipList":[
+						{"SdsIp":{"ip":"10.1.0.1","role":"sdsOnly"}},
+						{"SdsIp":{"ip":"10.2.0.1","role":"sdcOnly"}}]}]`))
+			case "/api/instances/Sds::sds1":
+				resp.Write([]byte(`{"id":"sds1","protectionDomainId":"pd1"}`))
+			case "/api/instances/StoragePool::sp1/relationships/Device":
+				resp.Write([]byte(`[
+					{"id":"dev1","name":"d1","deviceCurrentPathname":"/dev/sdb","storagePoolId":"sp1","sdsId":"sds1"},
+					{"id":"dev2","name":"d2","deviceCurrentPathname":"/dev/sdb","storagePoolId":"sp1","sdsId":"sds2"}]`))
+			case "/api/instances/Device::dev3":
+				resp.Write([]byte(`{"id":"dev3","storagePoolId":"sp2"}`))
+			default:
+				resp.Write([]byte(`{}`))
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	system := NewSystem(client)
+	system.System.ID = "sys1"
+
+	sdc, err := system.FindSdc("Name", "host1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdc.Sdc.ID != "sdc1" {
+		t.Fatal("Expecting sdc1, got", sdc.Sdc.ID)
+	}
+	if _, err := system.FindSdcByID("other"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+	if sdc, err = system.FindSdcByGUID("guid-2"); err != nil {
+		t.Fatal(err)
+	}
+	if sdc.Sdc.ID != "sdc2" {
+		t.Fatal("Expecting sdc2, got", sdc.Sdc.ID)
+	}
+	if _, err := system.FindSdcByIP("10.0.0.2"); !errors.Is(err, ErrAmbiguous) {
+		t.Fatal("Expecting ErrAmbiguous, got", err)
+	}
+	if _, err := system.FindSdc("SdcIp", "10.0.0.9"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+	if _, err := system.FindSdc("Rank", "1"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	// queries such as queryIdByKey leave the listing in place
+	if _, err := system.FindSdcByName("host1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := system.FindSdcByGUID("GUID-1"); err != nil {
+		t.Fatal(err)
+	}
+	if listings != 1 {
+		t.Fatal("Expecting the SDC listing to be reused, got", listings)
+	}
+
+	// a POST may change the listing, so the next lookup fetches it again
+	if err := NewSdc(client, &types.Sdc{ID: "sdc3"}).SetSdcName("host3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := system.FindSdcByGUID("GUID-3"); err != nil {
+		t.Fatal(err)
+	}
+	if listings != 2 {
+		t.Fatal("Expecting the SDC listing to be fetched again, got", listings)
+	}
+
+	pd := NewProtectionDomainEx(client, &types.ProtectionDomain{ID: "pd1"})
+	sds, err := pd.FindSds("IP", "10.2.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sds.ID != "sds1" {
+		t.Fatal("Expecting sds1, got", sds.ID)
+	}
+	if _, err := pd.FindSdsByID("sds1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pd.FindSdsByIP("10.9.0.1"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+
+	sp := NewStoragePoolEx(client, &types.StoragePool{ID: "sp1"})
+	device, err := sp.FindDevice("Name", "d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.ID != "dev2" {
+		t.Fatal("Expecting dev2, got", device.ID)
+	}
+	if _, err := sp.FindDevice("DeviceCurrentPathname", "/dev/sdb"); !errors.Is(err, ErrAmbiguous) {
+		t.Fatal("Expecting ErrAmbiguous, got", err)
+	}
+	if device, err = sp.FindDeviceByPath("sds1", "/dev/sdb"); err != nil {
+		t.Fatal(err)
+	}
+	if device.ID != "dev1" {
+		t.Fatal("Expecting dev1, got", device.ID)
+	}
+	if _, err := sp.FindDeviceByID("dev3"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+}
+
+func TestSdsLifecycle(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		actions []string
+		removed bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if req.RequestURI == "/api/instances/Sds::sds1" {
+				if removed {
+					resp.WriteHeader(http.StatusInternalServerError)
+					resp.Write([]byte(`{"message":"Could not find the SDS","httpStatusCode":500,"errorCode":0}`))
+					return
+				}
+				resp.Write([]byte(`{"id":"sds1","name":"node2","port":7073}`))
+				return
+			}
+
+			body, _ := ioutil.ReadAll(req.Body)
+			action := path.Base(req.RequestURI)
+			actions = append(actions,
+				action+" "+string(bytes.TrimSpace(body)))
+			if action == "removeSds" {
+				removed = true
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sds := NewSdsEx(client, &types.Sds{ID: "sds1"})
+	refreshed, err := sds.SetSdsName("node2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Name != "node2" || sds.Sds.Name != "node2" {
+		t.Fatal("Expecting the refreshed SDS, got", refreshed)
+	}
+	if _, err := sds.AddSdsIP("10.0.0.3", "storage"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if _, err := sds.AddSdsIP("10.0.0.3", SdsIPRoleSdsOnly); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sds.SetSdsIPRole("", SdsIPRoleAll); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if _, err := sds.SetSdsIPRole("10.0.0.3", SdsIPRoleAll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sds.RemoveSdsIP(""); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if _, err := sds.RemoveSdsIP("10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sds.SetSdsPort(0); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+	if refreshed, err = sds.SetSdsPort(7073); err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Port != 7073 {
+		t.Fatal("Expecting port 7073, got", refreshed.Port)
+	}
+	if refreshed, err = sds.RemoveSds(true); err != nil {
+		t.Fatal(err)
+	}
+	if refreshed != nil {
+		t.Fatal("Expecting no SDS after removing it, got", refreshed)
+	}
+
+	expected := []string{
+		`setSdsName {"name":"node2"}`,
+		`addSdsIp {"ip":"10.0.0.3","role":"sdsOnly"}`,
+		`setSdsIpRole {"sdsIpToSet":"10.0.0.3","newRole":"all"}`,
+		`removeSdsIp {"ip":"10.0.0.3"}`,
+		`setSdsPort {"sdsPort":"7073"}`,
+		`removeSds {"force":"TRUE"}`,
+	}
+	if strings.Join(actions, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expecting actions\n%s\ngot\n%s",
+			strings.Join(expected, "\n"), strings.Join(actions, "\n"))
+	}
+}
+
+func TestProtectionDomainCreateSds(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		params []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/types/Sds/instances":
+				body, _ := ioutil.ReadAll(req.Body)
+				mu.Lock()
+				params = append(params, string(bytes.TrimSpace(body)))
+				mu.Unlock()
+				resp.Write([]byte(`{"id":"sds1"}`))
+			case "/api/instances/Sds::sds1":
+				resp.Write([]byte(`{"id":"sds1","name":"node1","protectionDomainId":"pd1","port":7072}`))
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pd := NewProtectionDomainEx(client, &types.ProtectionDomain{ID: "pd1"})
+	if _, err := pd.CreateSds("node1",
+		[]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("Expecting ErrInvalidArgument, got", err)
+	}
+
+	ipList := func(ips ...string) []*types.SdsIpList {
+		var list []*types.SdsIpList
+		for i := 0; i < len(ips); i += 2 {
+			list = append(list, &types.SdsIpList{
+				SdsIP: types.SdsIp{IP: ips[i], Role: ips[i+1]}})
+		}
+		return list
+	}
+
+	for _, invalid := range [][]*types.SdsIpList{
+		nil,
+		ipList("10.0.0.1", "storage"),
+		ipList("10.0.0.1", SdsIPRoleAll, "10.0.0.1", SdsIPRoleSdcOnly),
+		ipList("10.0.0.1", SdsIPRoleSdcOnly, "10.0.0.2", SdsIPRoleSdcOnly),
+	} {
+		_, err := pd.CreateSdsWithParams(&types.SdsParam{IPList: invalid})
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fatal("Expecting ErrInvalidArgument, got", err)
+		}
+	}
+
+	sdsParam := &types.SdsParam{
+		Name: "node1",
+		IPList: ipList(
+			"10.0.0.1", SdsIPRoleSdcOnly,
+			"10.0.0.2", SdsIPRoleSdcOnly,
+			"10.1.0.1", SdsIPRoleSdsOnly,
+			"10.2.0.1", SdsIPRoleAll),
+		Port: 7072,
+	}
+	sds, err := pd.CreateSdsWithParams(sdsParam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdsParam.ProtectionDomainID != "" {
+		t.Fatal("Expecting the param to be left as it is, got",
+			sdsParam.ProtectionDomainID)
+	}
+	if sds.ID != "sds1" || sds.Port != 7072 {
+		t.Fatal("Expecting the created SDS, got", sds)
+	}
+
+	if len(params) != 1 {
+		t.Fatal("Expecting one SDS to be created, got", params)
+	}
+	expected := `{"name":"node1","sdsIpList":[` +
+		`{"SdsIp":{"ip":"10.0.0.1","role":"sdcOnly"}},` +
+		`{"SdsIp":{"ip":"10.0.0.2","role":"sdcOnly"}},` +
+		`{"SdsIp":{"ip":"10.1.0.1","role":"sdsOnly"}},` +
+		`{"SdsIp":{"ip":"10.2.0.1","role":"all"}}],` +
+		`"sdsPort":7072,"protectionDomainId":"pd1"}`
+	if params[0] != expected {
+		t.Fatalf("Expecting\n%s\ngot\n%s", expected, params[0])
+	}
+}
+
+func TestFindSdcDuringRename(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		renamed bool
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/instances/System::sys1/relationships/Sdc":
+				mu.Lock()
+				name := "host1"
+				if renamed {
+					name = "host2"
+				}
+				mu.Unlock()
+				resp.Write([]byte(`[{"id":"sdc1","name":"` + name +
+					`","sdcGuid":"GUID-1"}]`))
+			case "/api/instances/Sdc::sdc1/action/setSdcName":
+				close(started)
+				<-release
+				mu.Lock()
+				renamed = true
+				mu.Unlock()
+			}
+		},
+	))
+	defer server.Close()
+
+	client, err := NewClientWithArgs(server.URL+"/api", "2.0", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	system := NewSystem(client)
+	system.System.ID = "sys1"
+
+	done := make(chan error)
+	go func() {
+		done <- NewSdc(client,
+			&types.Sdc{ID: "sdc1", Name: "host1"}).SetSdcName("host2")
+	}()
+
+	// a lookup while the rename is in flight sees the old name
+	<-started
+	sdc, err := system.FindSdcByGUID("GUID-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdc.Sdc.Name != "host1" {
+		t.Fatal("Expecting host1, got", sdc.Sdc.Name)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// but must not be served once the rename has returned
+	if sdc, err = system.FindSdcByGUID("GUID-1"); err != nil {
+		t.Fatal(err)
+	}
+	if sdc.Sdc.Name != "host2" {
+		t.Fatal("Expecting host2, got", sdc.Sdc.Name)
+	}
+}
+
+func TestListingCacheGeneration(t *testing.T) {
+	var lc listingCache
+
+	generation := lc.currentGeneration()
+	lc.invalidate(context.Background(), http.MethodPost)
+	lc.put("/listing", json.RawMessage(`[]`), generation)
+	if _, ok := lc.get("/listing"); ok {
+		t.Fatal("Expecting a listing requested before invalidation to be dropped")
+	}
+
+	lc.invalidate(withQuery(context.Background()), http.MethodPost)
+	lc.put("/listing", json.RawMessage(`[]`), lc.currentGeneration())
+	lc.invalidate(withQuery(context.Background()), http.MethodPost)
+	if _, ok := lc.get("/listing"); !ok {
+		t.Fatal("Expecting a query to leave the listing in place")
+	}
+}
@@ -1,10 +1,9 @@
 package goscaleio
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net/http"
-	"reflect"
 
 	types "github.com/thecodeteam/goscaleio/types/v1"
 )
@@ -32,8 +31,16 @@ func (sp *StoragePool) AttachDevice(
 	path string,
 	sdsID string) (string, error) {
 
+	return sp.AttachDeviceCtx(context.Background(), path, sdsID)
+}
+
+func (sp *StoragePool) AttachDeviceCtx(
+	ctx context.Context,
+	path string,
+	sdsID string) (string, error) {
+
 	deviceParam := &types.DeviceParam{
-		Name: path,
+		Name:                  path,
 		DeviceCurrentPathname: path,
 		StoragePoolID:         sp.StoragePool.ID,
 		SdsID:                 sdsID,
@@ -41,7 +48,7 @@ func (sp *StoragePool) AttachDevice(
 
 	dev := types.DeviceResp{}
 	err := sp.client.getJSONWithRetry(
-		http.MethodPost, "/api/types/Device/instances",
+		ctx, http.MethodPost, "/api/types/Device/instances",
 		deviceParam, &dev)
 	if err != nil {
 		return "", err
@@ -51,6 +58,11 @@ func (sp *StoragePool) AttachDevice(
 }
 
 func (sp *StoragePool) GetDevice() ([]types.Device, error) {
+	return sp.GetDeviceCtx(context.Background())
+}
+
+func (sp *StoragePool) GetDeviceCtx(
+	ctx context.Context) ([]types.Device, error) {
 
 	path := fmt.Sprintf(
 		"/api/instances/StoragePool::%v/relationships/Device",
@@ -58,7 +70,7 @@ func (sp *StoragePool) GetDevice() ([]types.Device, error) {
 
 	var devices []types.Device
 	err := sp.client.getJSONWithRetry(
-		http.MethodGet, path, nil, &devices)
+		ctx, http.MethodGet, path, nil, &devices)
 	if err != nil {
 		return nil, err
 	}
@@ -66,21 +78,110 @@ func (sp *StoragePool) GetDevice() ([]types.Device, error) {
 	return devices, nil
 }
 
+// FindDevice returns the device in the storage pool whose field, one of
+// ID, Name and DeviceCurrentPathname, is value.
 func (sp *StoragePool) FindDevice(
 	field, value string) (*types.Device, error) {
 
-	devices, err := sp.GetDevice()
+	return sp.FindDeviceCtx(context.Background(), field, value)
+}
+
+func (sp *StoragePool) FindDeviceCtx(
+	ctx context.Context,
+	field, value string) (*types.Device, error) {
+
+	switch field {
+	case "ID":
+		return sp.FindDeviceByIDCtx(ctx, value)
+	case "Name":
+		return sp.FindDeviceByNameCtx(ctx, value)
+	case "DeviceCurrentPathname":
+		return sp.FindDeviceByPathCtx(ctx, "", value)
+	}
+
+	return nil, fmt.Errorf("%w: cannot find device by %s",
+		ErrInvalidArgument, field)
+}
+
+func (sp *StoragePool) FindDeviceByID(id string) (*types.Device, error) {
+	return sp.FindDeviceByIDCtx(context.Background(), id)
+}
+
+func (sp *StoragePool) FindDeviceByIDCtx(
+	ctx context.Context, id string) (*types.Device, error) {
+
+	path := fmt.Sprintf("/api/instances/Device::%v", id)
+
+	device := &types.Device{}
+	err := sp.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, device)
 	if err != nil {
 		return nil, err
 	}
+	if device.StoragePoolID != sp.StoragePool.ID {
+		return nil, newNotFound("Couldn't find device")
+	}
+
+	return device, nil
+}
+
+func (sp *StoragePool) FindDeviceByName(name string) (*types.Device, error) {
+	return sp.FindDeviceByNameCtx(context.Background(), name)
+}
+
+func (sp *StoragePool) FindDeviceByNameCtx(
+	ctx context.Context, name string) (*types.Device, error) {
+
+	return sp.findDevice(ctx, func(device *types.Device) bool {
+		return device.Name == name
+	})
+}
+
+// FindDeviceByPath returns the device in the storage pool at path on the
+// SDS sdsID, or on any SDS if sdsID is empty.
+func (sp *StoragePool) FindDeviceByPath(
+	sdsID, path string) (*types.Device, error) {
+
+	return sp.FindDeviceByPathCtx(context.Background(), sdsID, path)
+}
+
+func (sp *StoragePool) FindDeviceByPathCtx(
+	ctx context.Context, sdsID, path string) (*types.Device, error) {
+
+	return sp.findDevice(ctx, func(device *types.Device) bool {
+		return device.DeviceCurrentPathname == path &&
+			(sdsID == "" || device.SdsID == sdsID)
+	})
+}
+
+// findDevice returns the only device in a recent listing for which match
+// is true.
+func (sp *StoragePool) findDevice(
+	ctx context.Context,
+	match func(device *types.Device) bool) (*types.Device, error) {
+
+	path := fmt.Sprintf(
+		"/api/instances/StoragePool::%v/relationships/Device",
+		sp.StoragePool.ID)
+
+	var devices []*types.Device
+	if err := sp.client.getCachedListing(ctx, path, &devices); err != nil {
+		return nil, err
+	}
 
+	var found *types.Device
 	for _, device := range devices {
-		valueOf := reflect.ValueOf(device)
-		switch {
-		case reflect.Indirect(valueOf).FieldByName(field).String() == value:
-			return &device, nil
+		if !match(device) {
+			continue
 		}
+		if found != nil {
+			return nil, newAmbiguous("More than one device matches")
+		}
+		found = device
+	}
+	if found == nil {
+		return nil, newNotFound("Couldn't find device")
 	}
 
-	return nil, errors.New("Couldn't find DEV")
+	return found, nil
 }
@@ -0,0 +1,134 @@
+package goscaleio
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+var (
+	// ErrAuthentication is returned when the gateway rejects the
+	// credentials passed to Authenticate.
+	ErrAuthentication = errors.New("authentication failed")
+
+	// ErrUnauthorized is returned when the gateway rejects a request even
+	// after logging in again.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is returned when the user is not allowed to perform
+	// the request.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrNotFound is returned when the requested object does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrAlreadyExists is returned when an object with the same name or
+	// key already exists.
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrInsufficientCapacity is returned when a storage pool does not
+	// have enough free capacity for the request.
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
+
+	// ErrVolumeMapped is returned when a volume cannot be changed or
+	// removed because it is mapped to an SDC, or is already mapped to the
+	// requested SDC.
+	ErrVolumeMapped = errors.New("volume is mapped")
+
+	// ErrInvalidArgument is returned, without contacting the gateway,
+	// when an argument is known to be rejected by it.
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrAmbiguous is returned when a lookup matches more than one object.
+	ErrAmbiguous = errors.New("ambiguous")
+)
+
+// wrappedError ties an error to one of the sentinel errors above so
+// callers can test for it with errors.Is while still reaching the
+// underlying *types.Error with errors.As. Its message is the message of
+// the underlying error.
+type wrappedError struct {
+	sentinel error
+	err      error
+}
+
+func (e *wrappedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+func newNotFound(msg string) error {
+	return &wrappedError{sentinel: ErrNotFound, err: errors.New(msg)}
+}
+
+func newAmbiguous(msg string) error {
+	return &wrappedError{sentinel: ErrAmbiguous, err: errors.New(msg)}
+}
+
+// errorMessages maps fragments of the gateway's error messages to
+// sentinel errors. The gateway reports most failures as a 500 regardless
+// of their cause, and its ScaleIO error codes are not documented well
+// enough to rely on, so the message is the only indication left. Its
+// wording may change between releases.
+var errorMessages = []struct {
+	fragments []string
+	sentinel  error
+}{
+	{[]string{"not found", "could not find", "does not exist"},
+		ErrNotFound},
+	{[]string{"already exists", "already in use"},
+		ErrAlreadyExists},
+	{[]string{"insufficient capacity", "not enough capacity",
+		"not enough space", "insufficient space"},
+		ErrInsufficientCapacity},
+	{[]string{"already mapped", "still mapped", "is mapped to",
+		"has mapped", "volume is mapped"},
+		ErrVolumeMapped},
+}
+
+// classifyError wraps a *types.Error returned by the gateway in the
+// sentinel error that matches its HTTP status code or, failing that, its
+// message. Other errors are returned unchanged.
+func classifyError(err error) error {
+	e, ok := err.(*types.Error)
+	if !ok {
+		return err
+	}
+
+	if sentinel := sentinelFor(e); sentinel != nil {
+		return &wrappedError{sentinel: sentinel, err: e}
+	}
+	return e
+}
+
+func sentinelFor(e *types.Error) error {
+	switch e.HTTPStatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrAlreadyExists
+	}
+
+	msg := strings.ToLower(e.Message)
+	for _, m := range errorMessages {
+		for _, f := range m.fragments {
+			if strings.Contains(msg, f) {
+				return m.sentinel
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package goscaleio
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  *types.Error
+		want error
+	}{
+		{&types.Error{Message: "Unauthorized", HTTPStatusCode: 401}, ErrUnauthorized},
+		{&types.Error{Message: "Forbidden", HTTPStatusCode: 403}, ErrForbidden},
+		{&types.Error{Message: "Not found", HTTPStatusCode: 500}, ErrNotFound},
+		{&types.Error{Message: "Could not find the volume", HTTPStatusCode: 500}, ErrNotFound},
+		{&types.Error{Message: "Volume name already in use. Please use a different name.", HTTPStatusCode: 500}, ErrAlreadyExists},
+		{&types.Error{Message: "Insufficient capacity in storage pool", HTTPStatusCode: 500}, ErrInsufficientCapacity},
+		{&types.Error{Message: "The volume is already mapped to this SDC", HTTPStatusCode: 500}, ErrVolumeMapped},
+		{&types.Error{Message: "Internal error", HTTPStatusCode: 500}, nil},
+		{&types.Error{Message: "Internal error", HTTPStatusCode: 500, ErrorCode: 3}, nil},
+	}
+
+	for _, tt := range tests {
+		err := classifyError(tt.err)
+		if tt.want == nil && err != error(tt.err) {
+			t.Errorf("%q: expecting no sentinel, got %v", tt.err.Message, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%q: expecting %v, got %v", tt.err.Message, tt.want, err)
+		}
+		if err.Error() != tt.err.Message {
+			t.Errorf("%q: expecting message to be kept, got %q",
+				tt.err.Message, err.Error())
+		}
+		var apiErr *types.Error
+		if !errors.As(err, &apiErr) || apiErr != tt.err {
+			t.Errorf("%q: expecting to unwrap to *types.Error", tt.err.Message)
+		}
+	}
+}
+
+func TestFindStoragePoolNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(resp http.ResponseWriter, req *http.Request) {
+			switch req.RequestURI {
+			case "/api/version":
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`"2.0"`))
+			case "/api/login":
+				handleAuthToken(resp, req)
+			case "/api/types/StoragePool/instances":
+				resp.WriteHeader(http.StatusOK)
+				resp.Write([]byte(`[{"id":"pool1","name":"pool1"}]`))
+			case "/api/types/Volume/instances/action/queryIdByKey":
+				resp.WriteHeader(http.StatusInternalServerError)
+				resp.Write([]byte(`{"message":"Not found","httpStatusCode":500,"errorCode":3}`))
+			default:
+				t.Fatal("Unexpected endpoint", req.RequestURI)
+			}
+		},
+	))
+	defer server.Close()
+	client := setupClient(t, server.URL)
+
+	_, err := client.FindStoragePool("", "pool2", "")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+
+	_, err = client.FindVolumeID("vol1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expecting ErrNotFound, got", err)
+	}
+
+	vols, err := client.GetVolume("", "", "", "vol1", false)
+	if err != nil || vols != nil {
+		t.Fatal("Expecting no volumes and no error, got", vols, err)
+	}
+}
@@ -1,6 +1,7 @@
 package goscaleio
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,6 +10,11 @@ import (
 )
 
 func (c *Client) GetInstance(systemhref string) ([]*types.System, error) {
+	return c.GetInstanceCtx(context.Background(), systemhref)
+}
+
+func (c *Client) GetInstanceCtx(
+	ctx context.Context, systemhref string) ([]*types.System, error) {
 
 	var (
 		err     error
@@ -18,10 +24,10 @@ func (c *Client) GetInstance(systemhref string) ([]*types.System, error) {
 
 	if systemhref == "" {
 		err = c.getJSONWithRetry(
-			http.MethodGet, "api/types/System/instances", nil, &systems)
+			ctx, http.MethodGet, "api/types/System/instances", nil, &systems)
 	} else {
 		err = c.getJSONWithRetry(
-			http.MethodGet, systemhref, nil, system)
+			ctx, http.MethodGet, systemhref, nil, system)
 	}
 	if err != nil {
 		return nil, err
@@ -34,10 +40,21 @@ func (c *Client) GetInstance(systemhref string) ([]*types.System, error) {
 	return systems, nil
 }
 
+// Deprecated: use ListVolumes, or GetVolumesByIDs for volumes whose IDs
+// are known.
 func (c *Client) GetVolume(
 	volumehref, volumeid, ancestorvolumeid, volumename string,
 	getSnapshots bool) ([]*types.Volume, error) {
 
+	return c.GetVolumeCtx(context.Background(), volumehref, volumeid,
+		ancestorvolumeid, volumename, getSnapshots)
+}
+
+func (c *Client) GetVolumeCtx(
+	ctx context.Context,
+	volumehref, volumeid, ancestorvolumeid, volumename string,
+	getSnapshots bool) ([]*types.Volume, error) {
+
 	var (
 		err     error
 		path    string
@@ -46,12 +63,12 @@ func (c *Client) GetVolume(
 	)
 
 	if volumename != "" {
-		volumeid, err = c.FindVolumeID(volumename)
-		if err != nil && err.Error() == "Not found" {
+		volumeid, err = c.FindVolumeIDCtx(ctx, volumename)
+		if errors.Is(err, ErrNotFound) {
 			return nil, nil
 		}
 		if err != nil {
-			return nil, fmt.Errorf("Error: problem finding volume: %s", err)
+			return nil, fmt.Errorf("Error: problem finding volume: %w", err)
 		}
 	}
 
@@ -65,10 +82,10 @@ func (c *Client) GetVolume(
 
 	if volumehref == "" && volumeid == "" {
 		err = c.getJSONWithRetry(
-			http.MethodGet, path, nil, &volumes)
+			ctx, http.MethodGet, path, nil, &volumes)
 	} else {
 		err = c.getJSONWithRetry(
-			http.MethodGet, path, nil, volume)
+			ctx, http.MethodGet, path, nil, volume)
 
 	}
 	if err != nil {
@@ -89,7 +106,115 @@ func (c *Client) GetVolume(
 	return volumes, nil
 }
 
+// volumeQueryChunkSize is the number of volume IDs sent in one
+// queryBySelectedIds request.
+const volumeQueryChunkSize = 100
+
+// GetVolumesByIDs returns the volumes with the given IDs keyed by ID, in
+// as few requests as possible, and the IDs of the volumes that do not
+// exist.
+func (c *Client) GetVolumesByIDs(
+	ids []string) (map[string]*types.Volume, []string, error) {
+
+	return c.GetVolumesByIDsCtx(context.Background(), ids)
+}
+
+func (c *Client) GetVolumesByIDsCtx(
+	ctx context.Context,
+	ids []string) (map[string]*types.Volume, []string, error) {
+
+	var (
+		unique  []string
+		seen    = make(map[string]bool, len(ids))
+		volumes = make(map[string]*types.Volume, len(ids))
+		missing []string
+	)
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	for start := 0; start < len(unique); start += volumeQueryChunkSize {
+		end := start + volumeQueryChunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		chunk := unique[start:end]
+
+		found, err := c.queryVolumesBySelectedIDs(ctx, chunk)
+		if errors.Is(err, ErrNotFound) {
+			// the gateway rejects the whole chunk if one ID does not
+			// exist, so fall back to asking for every volume on its own
+			found, err = c.getVolumesOneByOne(ctx, chunk)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, volume := range found {
+			volumes[volume.ID] = volume
+		}
+		for _, id := range chunk {
+			if _, ok := volumes[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+
+	return volumes, missing, nil
+}
+
+func (c *Client) queryVolumesBySelectedIDs(
+	ctx context.Context, ids []string) ([]*types.Volume, error) {
+
+	volumeQeryBySelectedIdsParam := &types.VolumeQeryBySelectedIdsParam{
+		IDs: ids,
+	}
+
+	path := "/api/types/Volume/instances/action/queryBySelectedIds"
+
+	var volumes []*types.Volume
+	err := c.getJSONWithRetry(
+		withQuery(ctx), http.MethodPost, path,
+		volumeQeryBySelectedIdsParam, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
+// getVolumesOneByOne returns the volumes with the given IDs that exist.
+func (c *Client) getVolumesOneByOne(
+	ctx context.Context, ids []string) ([]*types.Volume, error) {
+
+	var volumes []*types.Volume
+	for _, id := range ids {
+		path := fmt.Sprintf("/api/instances/Volume::%s", id)
+
+		volume := &types.Volume{}
+		err := c.getJSONWithRetry(
+			ctx, http.MethodGet, path, nil, volume)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, nil
+}
+
 func (c *Client) FindVolumeID(volumename string) (string, error) {
+	return c.FindVolumeIDCtx(context.Background(), volumename)
+}
+
+func (c *Client) FindVolumeIDCtx(
+	ctx context.Context, volumename string) (string, error) {
 
 	volumeQeryIdByKeyParam := &types.VolumeQeryIdByKeyParam{
 		Name: volumename,
@@ -97,7 +222,8 @@ func (c *Client) FindVolumeID(volumename string) (string, error) {
 
 	path := fmt.Sprintf("/api/types/Volume/instances/action/queryIdByKey")
 
-	volumeID, err := c.getStringWithRetry(http.MethodPost, path,
+	volumeID, err := c.getStringWithRetry(
+		withQuery(ctx), http.MethodPost, path,
 		volumeQeryIdByKeyParam)
 	if err != nil {
 		return "", err
@@ -110,9 +236,17 @@ func (c *Client) CreateVolume(
 	volume *types.VolumeParam,
 	storagePoolName string) (*types.VolumeResp, error) {
 
+	return c.CreateVolumeCtx(context.Background(), volume, storagePoolName)
+}
+
+func (c *Client) CreateVolumeCtx(
+	ctx context.Context,
+	volume *types.VolumeParam,
+	storagePoolName string) (*types.VolumeResp, error) {
+
 	path := "/api/types/Volume/instances"
 
-	storagePool, err := c.FindStoragePool("", storagePoolName, "")
+	storagePool, err := c.FindStoragePoolCtx(ctx, "", storagePoolName, "")
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +256,7 @@ func (c *Client) CreateVolume(
 
 	vol := &types.VolumeResp{}
 	err = c.getJSONWithRetry(
-		http.MethodPost, path, volume, vol)
+		ctx, http.MethodPost, path, volume, vol)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +267,13 @@ func (c *Client) CreateVolume(
 func (c *Client) GetStoragePool(
 	storagepoolhref string) ([]*types.StoragePool, error) {
 
+	return c.GetStoragePoolCtx(context.Background(), storagepoolhref)
+}
+
+func (c *Client) GetStoragePoolCtx(
+	ctx context.Context,
+	storagepoolhref string) ([]*types.StoragePool, error) {
+
 	var (
 		err          error
 		storagePool  = &types.StoragePool{}
@@ -141,11 +282,11 @@ func (c *Client) GetStoragePool(
 
 	if storagepoolhref == "" {
 		err = c.getJSONWithRetry(
-			http.MethodGet, "/api/types/StoragePool/instances",
+			ctx, http.MethodGet, "/api/types/StoragePool/instances",
 			nil, &storagePools)
 	} else {
 		err = c.getJSONWithRetry(
-			http.MethodGet, storagepoolhref, nil, storagePool)
+			ctx, http.MethodGet, storagepoolhref, nil, storagePool)
 	}
 	if err != nil {
 		return nil, err
@@ -160,9 +301,16 @@ func (c *Client) GetStoragePool(
 func (c *Client) FindStoragePool(
 	id, name, href string) (*types.StoragePool, error) {
 
-	storagePools, err := c.GetStoragePool(href)
+	return c.FindStoragePoolCtx(context.Background(), id, name, href)
+}
+
+func (c *Client) FindStoragePoolCtx(
+	ctx context.Context,
+	id, name, href string) (*types.StoragePool, error) {
+
+	storagePools, err := c.GetStoragePoolCtx(ctx, href)
 	if err != nil {
-		return nil, fmt.Errorf("Error getting storage pool %s", err)
+		return nil, fmt.Errorf("Error getting storage pool %w", err)
 	}
 
 	for _, storagePool := range storagePools {
@@ -171,5 +319,5 @@ func (c *Client) FindStoragePool(
 		}
 	}
 
-	return nil, errors.New("Couldn't find storage pool")
+	return nil, newNotFound("Couldn't find storage pool")
 }
@@ -0,0 +1,111 @@
+package goscaleio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/thecodeteam/goscaleio/api"
+)
+
+// listingCacheTTL is how long a listing is reused by lookups that cannot
+// ask the gateway for a single object.
+const listingCacheTTL = 10 * time.Second
+
+// listingCache keeps the JSON of recent listings, keyed by path. Any
+// request other than a GET or a query may change what a listing holds, so
+// it empties the cache once it returns. The generation tells a listing
+// that was requested before then from one that reflects the change.
+type listingCache struct {
+	mu         sync.Mutex
+	generation uint64
+	entries    map[string]listingEntry
+}
+
+type listingEntry struct {
+	body    json.RawMessage
+	expires time.Time
+}
+
+func (lc *listingCache) get(path string) (json.RawMessage, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry, ok := lc.entries[path]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (lc *listingCache) currentGeneration() uint64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.generation
+}
+
+// put keeps the listing at path, unless the cache was emptied after the
+// listing was requested in generation.
+func (lc *listingCache) put(
+	path string, body json.RawMessage, generation uint64) {
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if generation != lc.generation {
+		return
+	}
+	if lc.entries == nil {
+		lc.entries = map[string]listingEntry{}
+	}
+	lc.entries[path] = listingEntry{
+		body:    body,
+		expires: time.Now().Add(listingCacheTTL),
+	}
+}
+
+func (lc *listingCache) invalidate(ctx context.Context, method string) {
+	if method == http.MethodGet || isQuery(ctx) {
+		return
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.generation++
+	lc.entries = nil
+}
+
+type queryKey struct{}
+
+// withQuery marks a POST request as one of the gateway's query actions.
+// Like api.WithIdempotent it may be retried, and it leaves the listing
+// cache in place.
+func withQuery(ctx context.Context) context.Context {
+	return api.WithIdempotent(context.WithValue(ctx, queryKey{}, true))
+}
+
+func isQuery(ctx context.Context) bool {
+	v, _ := ctx.Value(queryKey{}).(bool)
+	return v
+}
+
+// getCachedListing decodes the listing at path into resp, reusing a
+// recent copy of it if there is one.
+func (c *Client) getCachedListing(
+	ctx context.Context, path string, resp interface{}) error {
+
+	body, ok := c.listings.get(path)
+	if !ok {
+		generation := c.listings.currentGeneration()
+		err := c.getJSONWithRetry(
+			ctx, http.MethodGet, path, nil, &body)
+		if err != nil {
+			return err
+		}
+		c.listings.put(path, body, generation)
+	}
+
+	return json.Unmarshal(body, resp)
+}
@@ -1,7 +1,7 @@
 package goscaleio
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net/http"
 
@@ -28,6 +28,11 @@ func NewProtectionDomainEx(client *Client, pd *types.ProtectionDomain) *Protecti
 }
 
 func (s *System) CreateProtectionDomain(name string) (string, error) {
+	return s.CreateProtectionDomainCtx(context.Background(), name)
+}
+
+func (s *System) CreateProtectionDomainCtx(
+	ctx context.Context, name string) (string, error) {
 
 	protectionDomainParam := &types.ProtectionDomainParam{
 		Name: name,
@@ -37,7 +42,7 @@ func (s *System) CreateProtectionDomain(name string) (string, error) {
 
 	pd := types.ProtectionDomainResp{}
 	err := s.client.getJSONWithRetry(
-		http.MethodPost, path, protectionDomainParam, &pd)
+		ctx, http.MethodPost, path, protectionDomainParam, &pd)
 	if err != nil {
 		return "", err
 	}
@@ -48,6 +53,13 @@ func (s *System) CreateProtectionDomain(name string) (string, error) {
 func (s *System) GetProtectionDomain(
 	pdhref string) ([]*types.ProtectionDomain, error) {
 
+	return s.GetProtectionDomainCtx(context.Background(), pdhref)
+}
+
+func (s *System) GetProtectionDomainCtx(
+	ctx context.Context,
+	pdhref string) ([]*types.ProtectionDomain, error) {
+
 	var (
 		err error
 		pd  = &types.ProtectionDomain{}
@@ -64,10 +76,10 @@ func (s *System) GetProtectionDomain(
 		}
 
 		err = s.client.getJSONWithRetry(
-			http.MethodGet, link.HREF, nil, &pds)
+			ctx, http.MethodGet, link.HREF, nil, &pds)
 	} else {
 		err = s.client.getJSONWithRetry(
-			http.MethodGet, pdhref, nil, pd)
+			ctx, http.MethodGet, pdhref, nil, pd)
 	}
 	if err != nil {
 		return nil, err
@@ -82,9 +94,16 @@ func (s *System) GetProtectionDomain(
 func (s *System) FindProtectionDomain(
 	id, name, href string) (*types.ProtectionDomain, error) {
 
-	pds, err := s.GetProtectionDomain(href)
+	return s.FindProtectionDomainCtx(context.Background(), id, name, href)
+}
+
+func (s *System) FindProtectionDomainCtx(
+	ctx context.Context,
+	id, name, href string) (*types.ProtectionDomain, error) {
+
+	pds, err := s.GetProtectionDomainCtx(ctx, href)
 	if err != nil {
-		return nil, fmt.Errorf("Error getting protection domains %s", err)
+		return nil, fmt.Errorf("Error getting protection domains %w", err)
 	}
 
 	for _, pd := range pds {
@@ -93,5 +112,5 @@ func (s *System) FindProtectionDomain(
 		}
 	}
 
-	return nil, errors.New("Couldn't find protection domain")
+	return nil, newNotFound("Couldn't find protection domain")
 }
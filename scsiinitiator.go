@@ -1,6 +1,7 @@
 package goscaleio
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -8,6 +9,11 @@ import (
 )
 
 func (s *System) GetScsiInitiator() ([]types.ScsiInitiator, error) {
+	return s.GetScsiInitiatorCtx(context.Background())
+}
+
+func (s *System) GetScsiInitiatorCtx(
+	ctx context.Context) ([]types.ScsiInitiator, error) {
 
 	path := fmt.Sprintf(
 		"/api/instances/System::%v/relationships/ScsiInitiator",
@@ -15,7 +21,7 @@ func (s *System) GetScsiInitiator() ([]types.ScsiInitiator, error) {
 
 	var si []types.ScsiInitiator
 	err := s.client.getJSONWithRetry(
-		http.MethodGet, path, nil, &si)
+		ctx, http.MethodGet, path, nil, &si)
 	if err != nil {
 		return nil, err
 	}
@@ -1,13 +1,14 @@
 package goscaleio
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os/exec"
-	"reflect"
 	"strings"
 
+	"github.com/thecodeteam/goscaleio/api"
 	types "github.com/thecodeteam/goscaleio/types/v1"
 )
 
@@ -24,13 +25,17 @@ func NewSdc(client *Client, sdc *types.Sdc) *Sdc {
 }
 
 func (s *System) GetSdc() ([]types.Sdc, error) {
+	return s.GetSdcCtx(context.Background())
+}
+
+func (s *System) GetSdcCtx(ctx context.Context) ([]types.Sdc, error) {
 
 	path := fmt.Sprintf("/api/instances/System::%v/relationships/Sdc",
 		s.System.ID)
 
 	var sdcs []types.Sdc
 	err := s.client.getJSONWithRetry(
-		http.MethodGet, path, nil, &sdcs)
+		ctx, http.MethodGet, path, nil, &sdcs)
 	if err != nil {
 		return nil, err
 	}
@@ -38,42 +43,159 @@ func (s *System) GetSdc() ([]types.Sdc, error) {
 	return sdcs, nil
 }
 
+// FindSdc returns the SDC whose field, one of ID, Name, SdcGuid and
+// SdcIp, is value.
 func (s *System) FindSdc(field, value string) (*Sdc, error) {
+	return s.FindSdcCtx(context.Background(), field, value)
+}
+
+func (s *System) FindSdcCtx(
+	ctx context.Context, field, value string) (*Sdc, error) {
+
+	switch field {
+	case "ID":
+		return s.FindSdcByIDCtx(ctx, value)
+	case "Name":
+		return s.FindSdcByNameCtx(ctx, value)
+	case "SdcGuid":
+		return s.FindSdcByGUIDCtx(ctx, value)
+	case "SdcIp":
+		return s.FindSdcByIPCtx(ctx, value)
+	}
+
+	return nil, fmt.Errorf("%w: cannot find SDC by %s",
+		ErrInvalidArgument, field)
+}
+
+func (s *System) FindSdcByID(id string) (*Sdc, error) {
+	return s.FindSdcByIDCtx(context.Background(), id)
+}
 
-	sdcs, err := s.GetSdc()
+func (s *System) FindSdcByIDCtx(ctx context.Context, id string) (*Sdc, error) {
+
+	path := fmt.Sprintf("/api/instances/Sdc::%v", id)
+
+	sdc := &types.Sdc{}
+	err := s.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, sdc)
 	if err != nil {
 		return nil, err
 	}
+	if sdc.SystemID != "" && s.System.ID != "" &&
+		sdc.SystemID != s.System.ID {
+		return nil, newNotFound("Couldn't find SDC")
+	}
+
+	return NewSdc(s.client, sdc), nil
+}
+
+func (s *System) FindSdcByName(name string) (*Sdc, error) {
+	return s.FindSdcByNameCtx(context.Background(), name)
+}
+
+func (s *System) FindSdcByNameCtx(
+	ctx context.Context, name string) (*Sdc, error) {
+
+	queryIDByKeyParam := &types.QueryIdByKeyParam{
+		Name: name,
+	}
+
+	id, err := s.client.getStringWithRetry(
+		withQuery(ctx), http.MethodPost,
+		"/api/types/Sdc/instances/action/queryIdByKey", queryIDByKeyParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindSdcByIDCtx(ctx, id)
+}
+
+func (s *System) FindSdcByGUID(guid string) (*Sdc, error) {
+	return s.FindSdcByGUIDCtx(context.Background(), guid)
+}
+
+func (s *System) FindSdcByGUIDCtx(
+	ctx context.Context, guid string) (*Sdc, error) {
+
+	return s.findSdc(ctx, func(sdc *types.Sdc) bool {
+		return strings.EqualFold(sdc.SdcGuid, guid)
+	})
+}
+
+func (s *System) FindSdcByIP(ip string) (*Sdc, error) {
+	return s.FindSdcByIPCtx(context.Background(), ip)
+}
+
+func (s *System) FindSdcByIPCtx(ctx context.Context, ip string) (*Sdc, error) {
+	return s.findSdc(ctx, func(sdc *types.Sdc) bool {
+		return sdc.SdcIp == ip
+	})
+}
+
+// findSdc returns the only SDC in a recent listing for which match is
+// true.
+func (s *System) findSdc(
+	ctx context.Context, match func(sdc *types.Sdc) bool) (*Sdc, error) {
+
+	path := fmt.Sprintf("/api/instances/System::%v/relationships/Sdc",
+		s.System.ID)
+
+	var sdcs []*types.Sdc
+	if err := s.client.getCachedListing(ctx, path, &sdcs); err != nil {
+		return nil, err
+	}
 
+	var found *types.Sdc
 	for _, sdc := range sdcs {
-		valueOf := reflect.ValueOf(sdc)
-		switch {
-		case reflect.Indirect(valueOf).FieldByName(field).String() == value:
-			return NewSdc(s.client, &sdc), nil
+		if !match(sdc) {
+			continue
 		}
+		if found != nil {
+			return nil, newAmbiguous("More than one SDC matches")
+		}
+		found = sdc
+	}
+	if found == nil {
+		return nil, newNotFound("Couldn't find SDC")
 	}
 
-	return nil, errors.New("Couldn't find SDC")
+	return NewSdc(s.client, found), nil
 }
 
-func (sdc *Sdc) GetStatistics() (*types.Statistics, error) {
+// GetStatistics returns the statistics of the SDC. Its MdmConnectionState
+// is taken from the SDC if the gateway does not report it with the
+// statistics.
+func (sdc *Sdc) GetStatistics() (*types.SdcStatistics, error) {
+	return sdc.GetStatisticsCtx(context.Background())
+}
+
+func (sdc *Sdc) GetStatisticsCtx(
+	ctx context.Context) (*types.SdcStatistics, error) {
 
 	link, err := GetLink(sdc.Sdc.Links, "/api/Sdc/relationship/Statistics")
 	if err != nil {
 		return nil, err
 	}
 
-	var stats *types.Statistics
+	stats := types.SdcStatistics{}
 	err = sdc.client.getJSONWithRetry(
-		http.MethodGet, link.HREF, nil, stats)
+		ctx, http.MethodGet, link.HREF, nil, &stats)
 	if err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	if stats.MdmConnectionState == "" {
+		stats.MdmConnectionState = sdc.Sdc.MdmConnectionState
+	}
+
+	return &stats, nil
 }
 
 func (sdc *Sdc) GetVolume() ([]*types.Volume, error) {
+	return sdc.GetVolumeCtx(context.Background())
+}
+
+func (sdc *Sdc) GetVolumeCtx(ctx context.Context) ([]*types.Volume, error) {
 
 	link, err := GetLink(sdc.Sdc.Links, "/api/Sdc/relationship/Volume")
 	if err != nil {
@@ -82,7 +204,7 @@ func (sdc *Sdc) GetVolume() ([]*types.Volume, error) {
 
 	var vols []*types.Volume
 	err = sdc.client.getJSONWithRetry(
-		http.MethodGet, link.HREF, nil, &vols)
+		ctx, http.MethodGet, link.HREF, nil, &vols)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +212,168 @@ func (sdc *Sdc) GetVolume() ([]*types.Volume, error) {
 	return vols, nil
 }
 
+// The performance profiles an SDC can be set to.
+const (
+	SdcPerfProfileDefault         = "Default"
+	SdcPerfProfileHighPerformance = "HighPerformance"
+	SdcPerfProfileCompact         = "Compact"
+)
+
+// ApproveSdc approves the SDC with the GUID guid to connect to the system
+// when restricted SDC mode is enabled. Approving an SDC that is already
+// approved is not an error.
+func (s *System) ApproveSdc(guid string) error {
+	return s.ApproveSdcCtx(context.Background(), guid)
+}
+
+func (s *System) ApproveSdcCtx(ctx context.Context, guid string) error {
+
+	sdcs, err := s.GetSdcCtx(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sdc := range sdcs {
+		if strings.EqualFold(sdc.SdcGuid, guid) && sdc.SdcApproved {
+			return nil
+		}
+	}
+
+	path := fmt.Sprintf("/api/instances/System::%v/action/approveSdc",
+		s.System.ID)
+
+	approveSdcParam := &types.ApproveSdcParam{
+		SdcGUID: guid,
+	}
+
+	err = s.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path, approveSdcParam, nil)
+	if err != nil && !errors.Is(err, ErrAlreadyExists) {
+		return err
+	}
+
+	return nil
+}
+
+// SetSdcName renames the SDC, unless the gateway reports it already has
+// the name.
+func (sdc *Sdc) SetSdcName(name string) error {
+	return sdc.SetSdcNameCtx(context.Background(), name)
+}
+
+func (sdc *Sdc) SetSdcNameCtx(ctx context.Context, name string) error {
+
+	if name == "" {
+		return fmt.Errorf("%w: SDC name is required", ErrInvalidArgument)
+	}
+	if sdc.Sdc.Name == name {
+		// the wrapper may predate a rename made elsewhere
+		if err := sdc.refresh(ctx); err != nil {
+			return err
+		}
+		if sdc.Sdc.Name == name {
+			return nil
+		}
+	}
+
+	path := fmt.Sprintf("/api/instances/Sdc::%v/action/setSdcName",
+		sdc.Sdc.ID)
+
+	setSdcNameParam := &types.SetSdcNameParam{
+		SdcName: name,
+	}
+
+	err := sdc.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path, setSdcNameParam, nil)
+	if err != nil {
+		return err
+	}
+
+	sdc.Sdc.Name = name
+	return nil
+}
+
+// RemoveSdc removes the SDC from the system. Removing an SDC that no
+// longer exists is not an error.
+func (sdc *Sdc) RemoveSdc() error {
+	return sdc.RemoveSdcCtx(context.Background())
+}
+
+func (sdc *Sdc) RemoveSdcCtx(ctx context.Context) error {
+
+	path := fmt.Sprintf("/api/instances/Sdc::%v/action/removeSdc",
+		sdc.Sdc.ID)
+
+	err := sdc.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path, struct{}{}, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// SetSdcPerformanceProfile sets the performance profile of the SDC to
+// profile, one of SdcPerfProfileDefault, SdcPerfProfileHighPerformance
+// and SdcPerfProfileCompact, unless the gateway reports it is already
+// set.
+func (sdc *Sdc) SetSdcPerformanceProfile(profile string) error {
+	return sdc.SetSdcPerformanceProfileCtx(context.Background(), profile)
+}
+
+func (sdc *Sdc) SetSdcPerformanceProfileCtx(
+	ctx context.Context, profile string) error {
+
+	switch profile {
+	case SdcPerfProfileDefault, SdcPerfProfileHighPerformance,
+		SdcPerfProfileCompact:
+	default:
+		return fmt.Errorf("%w: unknown performance profile %q",
+			ErrInvalidArgument, profile)
+	}
+	if sdc.Sdc.PerfProfile == profile {
+		if err := sdc.refresh(ctx); err != nil {
+			return err
+		}
+		if sdc.Sdc.PerfProfile == profile {
+			return nil
+		}
+	}
+
+	path := fmt.Sprintf(
+		"/api/instances/Sdc::%v/action/setSdcPerformanceParameters",
+		sdc.Sdc.ID)
+
+	setSdcPerformanceParametersParam := &types.SetSdcPerformanceParametersParam{
+		PerfProfile: profile,
+	}
+
+	err := sdc.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path,
+		setSdcPerformanceParametersParam, nil)
+	if err != nil {
+		return err
+	}
+
+	sdc.Sdc.PerfProfile = profile
+	return nil
+}
+
+// refresh gets the SDC from the gateway and keeps it in sdc.Sdc.
+func (sdc *Sdc) refresh(ctx context.Context) error {
+
+	path := fmt.Sprintf("/api/instances/Sdc::%v", sdc.Sdc.ID)
+
+	current := &types.Sdc{}
+	err := sdc.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, current)
+	if err != nil {
+		return err
+	}
+
+	sdc.Sdc = current
+	return nil
+}
+
 func GetSdcLocalGUID() (string, error) {
 
 	// get sdc kernel guid
@@ -109,11 +393,18 @@ func GetSdcLocalGUID() (string, error) {
 func (v *Volume) MapVolumeSdc(
 	mapVolumeSdcParam *types.MapVolumeSdcParam) error {
 
+	return v.MapVolumeSdcCtx(context.Background(), mapVolumeSdcParam)
+}
+
+func (v *Volume) MapVolumeSdcCtx(
+	ctx context.Context,
+	mapVolumeSdcParam *types.MapVolumeSdcParam) error {
+
 	path := fmt.Sprintf("/api/instances/Volume::%s/action/addMappedSdc",
 		v.Volume.ID)
 
 	err := v.client.getJSONWithRetry(
-		http.MethodPost, path, mapVolumeSdcParam, nil)
+		ctx, http.MethodPost, path, mapVolumeSdcParam, nil)
 	if err != nil {
 		return err
 	}
@@ -124,11 +415,18 @@ func (v *Volume) MapVolumeSdc(
 func (v *Volume) UnmapVolumeSdc(
 	unmapVolumeSdcParam *types.UnmapVolumeSdcParam) error {
 
+	return v.UnmapVolumeSdcCtx(context.Background(), unmapVolumeSdcParam)
+}
+
+func (v *Volume) UnmapVolumeSdcCtx(
+	ctx context.Context,
+	unmapVolumeSdcParam *types.UnmapVolumeSdcParam) error {
+
 	path := fmt.Sprintf("/api/instances/Volume::%s/action/removeMappedSdc",
 		v.Volume.ID)
 
 	err := v.client.getJSONWithRetry(
-		http.MethodPost, path, unmapVolumeSdcParam, nil)
+		ctx, http.MethodPost, path, unmapVolumeSdcParam, nil)
 	if err != nil {
 		return err
 	}
@@ -139,12 +437,20 @@ func (v *Volume) UnmapVolumeSdc(
 func (v *Volume) SetMappedSdcLimits(
 	setMappedSdcLimitsParam *types.SetMappedSdcLimitsParam) error {
 
+	return v.SetMappedSdcLimitsCtx(
+		context.Background(), setMappedSdcLimitsParam)
+}
+
+func (v *Volume) SetMappedSdcLimitsCtx(
+	ctx context.Context,
+	setMappedSdcLimitsParam *types.SetMappedSdcLimitsParam) error {
+
 	path := fmt.Sprintf(
 		"/api/instances/Volume::%s/action/setMappedSdcLimits",
 		v.Volume.ID)
 
 	err := v.client.getJSONWithRetry(
-		http.MethodPost, path, setMappedSdcLimitsParam, nil)
+		ctx, http.MethodPost, path, setMappedSdcLimitsParam, nil)
 	if err != nil {
 		return err
 	}
@@ -1,11 +1,13 @@
 package goscaleio
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
+	"strconv"
 
+	"github.com/thecodeteam/goscaleio/api"
 	types "github.com/thecodeteam/goscaleio/types/v1"
 )
 
@@ -28,34 +30,111 @@ func NewSdsEx(client *Client, sds *types.Sds) *Sds {
 	}
 }
 
+// CreateSds adds an SDS named name to the protection domain and returns
+// its ID. One IP address is used for all traffic; of two, the first is
+// used by SDCs and the second by other SDSs. Use CreateSdsWithParams for
+// any other assignment of roles.
 func (pd *ProtectionDomain) CreateSds(
 	name string, ipList []string) (string, error) {
 
+	return pd.CreateSdsCtx(context.Background(), name, ipList)
+}
+
+func (pd *ProtectionDomain) CreateSdsCtx(
+	ctx context.Context,
+	name string, ipList []string) (string, error) {
+
 	sdsParam := &types.SdsParam{
-		Name:               name,
-		ProtectionDomainID: pd.ProtectionDomain.ID,
+		Name: name,
 	}
 
-	if len(ipList) == 0 {
+	switch len(ipList) {
+	case 0:
 		return "", fmt.Errorf("Must provide at least 1 SDS IP")
-	} else if len(ipList) == 1 {
-		sdsIP := types.SdsIp{IP: ipList[0], Role: "all"}
-		sdsIPList := &types.SdsIpList{SdsIP: sdsIP}
-		sdsParam.IPList = append(sdsParam.IPList, sdsIPList)
-	} else if len(ipList) >= 2 {
-		sdsIP1 := types.SdsIp{IP: ipList[0], Role: "sdcOnly"}
-		sdsIP2 := types.SdsIp{IP: ipList[1], Role: "sdsOnly"}
-		sdsIPList1 := &types.SdsIpList{SdsIP: sdsIP1}
-		sdsIPList2 := &types.SdsIpList{SdsIP: sdsIP2}
-		sdsParam.IPList = append(sdsParam.IPList, sdsIPList1)
-		sdsParam.IPList = append(sdsParam.IPList, sdsIPList2)
+	case 1:
+		sdsParam.IPList = []*types.SdsIpList{
+			{SdsIP: types.SdsIp{IP: ipList[0], Role: SdsIPRoleAll}},
+		}
+	case 2:
+		sdsParam.IPList = []*types.SdsIpList{
+			{SdsIP: types.SdsIp{IP: ipList[0], Role: SdsIPRoleSdcOnly}},
+			{SdsIP: types.SdsIp{IP: ipList[1], Role: SdsIPRoleSdsOnly}},
+		}
+	default:
+		return "", fmt.Errorf(
+			"%w: cannot assign roles to %d SDS IPs, use CreateSdsWithParams",
+			ErrInvalidArgument, len(ipList))
+	}
+
+	return pd.createSds(ctx, sdsParam)
+}
+
+// CreateSdsWithParams adds an SDS to the protection domain and returns
+// it. Every IP address in sdsParam.IPList has its own role, one of
+// SdsIPRoleAll, SdsIPRoleSdcOnly and SdsIPRoleSdsOnly, and at least one
+// of them must be usable by other SDSs. The SDS is created in pd
+// whatever the protection domain of sdsParam.
+func (pd *ProtectionDomain) CreateSdsWithParams(
+	sdsParam *types.SdsParam) (*types.Sds, error) {
+
+	return pd.CreateSdsWithParamsCtx(context.Background(), sdsParam)
+}
+
+func (pd *ProtectionDomain) CreateSdsWithParamsCtx(
+	ctx context.Context, sdsParam *types.SdsParam) (*types.Sds, error) {
+
+	if len(sdsParam.IPList) == 0 {
+		return nil, fmt.Errorf("%w: must provide at least 1 SDS IP",
+			ErrInvalidArgument)
+	}
+
+	var (
+		seen     = make(map[string]bool, len(sdsParam.IPList))
+		sdsReach bool
+	)
+	for _, ip := range sdsParam.IPList {
+		if ip == nil || ip.SdsIP.IP == "" {
+			return nil, fmt.Errorf("%w: SDS IP is required",
+				ErrInvalidArgument)
+		}
+		if seen[ip.SdsIP.IP] {
+			return nil, fmt.Errorf("%w: SDS IP %s is given more than once",
+				ErrInvalidArgument, ip.SdsIP.IP)
+		}
+		seen[ip.SdsIP.IP] = true
+
+		if err := validSdsIPRole(ip.SdsIP.Role); err != nil {
+			return nil, err
+		}
+		if ip.SdsIP.Role != SdsIPRoleSdcOnly {
+			sdsReach = true
+		}
+	}
+	if !sdsReach {
+		return nil, fmt.Errorf("%w: no SDS IP can be used by other SDSs",
+			ErrInvalidArgument)
+	}
+
+	id, err := pd.createSds(ctx, sdsParam)
+	if err != nil {
+		return nil, err
 	}
 
-	path := fmt.Sprintf("/api/types/Sds/instances")
+	return NewSdsEx(pd.client, &types.Sds{ID: id}).refresh(ctx)
+}
+
+func (pd *ProtectionDomain) createSds(
+	ctx context.Context, sdsParam *types.SdsParam) (string, error) {
+
+	// the caller's param is left as it is
+	param := *sdsParam
+	param.ProtectionDomainID = pd.ProtectionDomain.ID
+
+	path := "/api/types/Sds/instances"
 
 	sds := types.SdsResp{}
 	err := pd.client.getJSONWithRetry(
-		http.MethodPost, path, sdsParam, &sds)
+		ctx, http.MethodPost, path, &param, &sds)
 	if err != nil {
 		return "", err
 	}
@@ -64,13 +143,18 @@ func (pd *ProtectionDomain) CreateSds(
 }
 
 func (pd *ProtectionDomain) GetSds() ([]types.Sds, error) {
+	return pd.GetSdsCtx(context.Background())
+}
+
+func (pd *ProtectionDomain) GetSdsCtx(
+	ctx context.Context) ([]types.Sds, error) {
 
 	path := fmt.Sprintf("/api/instances/ProtectionDomain::%v/relationships/Sds",
 		pd.ProtectionDomain.ID)
 
 	var sdss []types.Sds
 	err := pd.client.getJSONWithRetry(
-		http.MethodGet, path, nil, &sdss)
+		ctx, http.MethodGet, path, nil, &sdss)
 	if err != nil {
 		return nil, err
 	}
@@ -78,21 +162,322 @@ func (pd *ProtectionDomain) GetSds() ([]types.Sds, error) {
 	return sdss, nil
 }
 
+// FindSds returns the SDS in the protection domain whose field, one of
+// ID, Name and IP, is value.
 func (pd *ProtectionDomain) FindSds(
 	field, value string) (*types.Sds, error) {
 
-	sdss, err := pd.GetSds()
+	return pd.FindSdsCtx(context.Background(), field, value)
+}
+
+func (pd *ProtectionDomain) FindSdsCtx(
+	ctx context.Context,
+	field, value string) (*types.Sds, error) {
+
+	switch field {
+	case "ID":
+		return pd.FindSdsByIDCtx(ctx, value)
+	case "Name":
+		return pd.FindSdsByNameCtx(ctx, value)
+	case "IP":
+		return pd.FindSdsByIPCtx(ctx, value)
+	}
+
+	return nil, fmt.Errorf("%w: cannot find SDS by %s",
+		ErrInvalidArgument, field)
+}
+
+func (pd *ProtectionDomain) FindSdsByID(id string) (*types.Sds, error) {
+	return pd.FindSdsByIDCtx(context.Background(), id)
+}
+
+func (pd *ProtectionDomain) FindSdsByIDCtx(
+	ctx context.Context, id string) (*types.Sds, error) {
+
+	path := fmt.Sprintf("/api/instances/Sds::%v", id)
+
+	sds := &types.Sds{}
+	err := pd.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, sds)
 	if err != nil {
 		return nil, err
 	}
+	if sds.ProtectionDomainID != pd.ProtectionDomain.ID {
+		return nil, newNotFound("Couldn't find SDS")
+	}
+
+	return sds, nil
+}
+
+func (pd *ProtectionDomain) FindSdsByName(name string) (*types.Sds, error) {
+	return pd.FindSdsByNameCtx(context.Background(), name)
+}
+
+func (pd *ProtectionDomain) FindSdsByNameCtx(
+	ctx context.Context, name string) (*types.Sds, error) {
+
+	queryIDByKeyParam := &types.QueryIdByKeyParam{
+		Name: name,
+	}
+
+	id, err := pd.client.getStringWithRetry(
+		withQuery(ctx), http.MethodPost,
+		"/api/types/Sds/instances/action/queryIdByKey", queryIDByKeyParam)
+	if err != nil {
+		return nil, err
+	}
+
+	return pd.FindSdsByIDCtx(ctx, id)
+}
+
+// FindSdsByIP returns the SDS in the protection domain that has the IP
+// address ip in any role.
+func (pd *ProtectionDomain) FindSdsByIP(ip string) (*types.Sds, error) {
+	return pd.FindSdsByIPCtx(context.Background(), ip)
+}
+
+func (pd *ProtectionDomain) FindSdsByIPCtx(
+	ctx context.Context, ip string) (*types.Sds, error) {
+
+	path := fmt.Sprintf(
+		"/api/instances/ProtectionDomain::%v/relationships/Sds",
+		pd.ProtectionDomain.ID)
+
+	var sdss []*types.Sds
+	if err := pd.client.getCachedListing(ctx, path, &sdss); err != nil {
+		return nil, err
+	}
 
+	var found *types.Sds
 	for _, sds := range sdss {
-		valueOf := reflect.ValueOf(sds)
-		switch {
-		case reflect.Indirect(valueOf).FieldByName(field).String() == value:
-			return &sds, nil
+		for _, ipList := range sds.IPList {
+			if ipList.SdsIP.IP != ip {
+				continue
+			}
+			if found != nil && found != sds {
+				return nil, newAmbiguous("More than one SDS matches")
+			}
+			found = sds
 		}
 	}
+	if found == nil {
+		return nil, newNotFound("Couldn't find SDS")
+	}
+
+	return found, nil
+}
+
+// The roles an SDS IP address can have.
+const (
+	SdsIPRoleAll     = "all"
+	SdsIPRoleSdcOnly = "sdcOnly"
+	SdsIPRoleSdsOnly = "sdsOnly"
+)
+
+func validSdsIPRole(role string) error {
+	switch role {
+	case SdsIPRoleAll, SdsIPRoleSdcOnly, SdsIPRoleSdsOnly:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown SDS IP role %q", ErrInvalidArgument, role)
+}
+
+// RemoveSds removes the SDS from its protection domain, and with force
+// even if its devices hold data that cannot be moved. It returns the SDS
+// as the gateway reports it afterwards, or nil once it is gone.
+func (s *Sds) RemoveSds(force bool) (*types.Sds, error) {
+	return s.RemoveSdsCtx(context.Background(), force)
+}
+
+func (s *Sds) RemoveSdsCtx(
+	ctx context.Context, force bool) (*types.Sds, error) {
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/removeSds",
+		s.Sds.ID)
+
+	removeSdsParam := &types.RemoveSdsParam{}
+	if force {
+		removeSdsParam.Force = "TRUE"
+	}
+
+	err := s.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path, removeSdsParam, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	sds, err := s.refresh(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return sds, err
+}
+
+// SetSdsName renames the SDS.
+func (s *Sds) SetSdsName(name string) (*types.Sds, error) {
+	return s.SetSdsNameCtx(context.Background(), name)
+}
+
+func (s *Sds) SetSdsNameCtx(
+	ctx context.Context, name string) (*types.Sds, error) {
+
+	if name == "" {
+		return nil, fmt.Errorf("%w: SDS name is required", ErrInvalidArgument)
+	}
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/setSdsName",
+		s.Sds.ID)
+
+	setSdsNameParam := &types.SetSdsNameParam{
+		Name: name,
+	}
+
+	err := s.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path, setSdsNameParam, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx)
+}
+
+// AddSdsIP adds the IP address ip with role, one of SdsIPRoleAll,
+// SdsIPRoleSdcOnly and SdsIPRoleSdsOnly, to the SDS.
+func (s *Sds) AddSdsIP(ip, role string) (*types.Sds, error) {
+	return s.AddSdsIPCtx(context.Background(), ip, role)
+}
+
+func (s *Sds) AddSdsIPCtx(
+	ctx context.Context, ip, role string) (*types.Sds, error) {
+
+	if ip == "" {
+		return nil, fmt.Errorf("%w: SDS IP is required", ErrInvalidArgument)
+	}
+	if err := validSdsIPRole(role); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/addSdsIp",
+		s.Sds.ID)
+
+	addSdsIPParam := &types.AddSdsIPParam{
+		IP:   ip,
+		Role: role,
+	}
+
+	err := s.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, addSdsIPParam, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx)
+}
+
+// RemoveSdsIP removes the IP address ip from the SDS.
+func (s *Sds) RemoveSdsIP(ip string) (*types.Sds, error) {
+	return s.RemoveSdsIPCtx(context.Background(), ip)
+}
+
+func (s *Sds) RemoveSdsIPCtx(
+	ctx context.Context, ip string) (*types.Sds, error) {
+
+	if ip == "" {
+		return nil, fmt.Errorf("%w: SDS IP is required", ErrInvalidArgument)
+	}
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/removeSdsIp",
+		s.Sds.ID)
+
+	removeSdsIPParam := &types.RemoveSdsIPParam{
+		IP: ip,
+	}
+
+	err := s.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, removeSdsIPParam, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx)
+}
+
+// SetSdsIPRole changes the role of the IP address ip of the SDS to role,
+// one of SdsIPRoleAll, SdsIPRoleSdcOnly and SdsIPRoleSdsOnly.
+func (s *Sds) SetSdsIPRole(ip, role string) (*types.Sds, error) {
+	return s.SetSdsIPRoleCtx(context.Background(), ip, role)
+}
+
+func (s *Sds) SetSdsIPRoleCtx(
+	ctx context.Context, ip, role string) (*types.Sds, error) {
+
+	if ip == "" {
+		return nil, fmt.Errorf("%w: SDS IP is required", ErrInvalidArgument)
+	}
+	if err := validSdsIPRole(role); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/setSdsIpRole",
+		s.Sds.ID)
+
+	setSdsIPRoleParam := &types.SetSdsIPRoleParam{
+		SdsIPToSet: ip,
+		NewRole:    role,
+	}
+
+	err := s.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path,
+		setSdsIPRoleParam, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx)
+}
+
+// SetSdsPort changes the port the SDS listens on.
+func (s *Sds) SetSdsPort(port int) (*types.Sds, error) {
+	return s.SetSdsPortCtx(context.Background(), port)
+}
+
+func (s *Sds) SetSdsPortCtx(
+	ctx context.Context, port int) (*types.Sds, error) {
+
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("%w: invalid SDS port %d",
+			ErrInvalidArgument, port)
+	}
+
+	path := fmt.Sprintf("/api/instances/Sds::%v/action/setSdsPort",
+		s.Sds.ID)
+
+	setSdsPortParam := &types.SetSdsPortParam{
+		SdsPort: strconv.Itoa(port),
+	}
+
+	err := s.client.getJSONWithRetry(
+		api.WithIdempotent(ctx), http.MethodPost, path,
+		setSdsPortParam, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refresh(ctx)
+}
+
+// refresh gets the SDS from the gateway and keeps it in s.Sds.
+func (s *Sds) refresh(ctx context.Context) (*types.Sds, error) {
+
+	path := fmt.Sprintf("/api/instances/Sds::%v", s.Sds.ID)
+
+	sds := &types.Sds{}
+	err := s.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, sds)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, errors.New("Couldn't find SDS")
+	s.Sds = sds
+	return sds, nil
 }
@@ -0,0 +1,278 @@
+package goscaleio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+// SnapshotTree is a volume and the snapshots taken of it, recursively.
+type SnapshotTree struct {
+	Volume    *types.Volume
+	Snapshots []*SnapshotTree
+}
+
+// Walk calls fn for every volume in the tree, parents before their
+// snapshots, with the depth of the volume below the root. It stops at the
+// first error fn returns.
+func (t *SnapshotTree) Walk(
+	fn func(vol *types.Volume, depth int) error) error {
+
+	return t.walk(fn, 0)
+}
+
+func (t *SnapshotTree) walk(
+	fn func(vol *types.Volume, depth int) error, depth int) error {
+
+	if err := fn(t.Volume, depth); err != nil {
+		return err
+	}
+	for _, snap := range t.Snapshots {
+		if err := snap.walk(fn, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSnapshot takes a snapshot of the volume named name and returns it.
+func (v *Volume) CreateSnapshot(name string) (*Volume, error) {
+	return v.CreateSnapshotCtx(context.Background(), name)
+}
+
+func (v *Volume) CreateSnapshotCtx(
+	ctx context.Context, name string) (*Volume, error) {
+
+	systems, err := v.client.GetInstanceCtx(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("err: problem getting instances: %w", err)
+	}
+	if len(systems) == 0 {
+		return nil, newNotFound("err: no system found")
+	}
+
+	system := NewSystem(v.client)
+	system.System = systems[0]
+
+	snapshotVolumesParam := &types.SnapshotVolumesParam{
+		SnapshotDefs: []*types.SnapshotDef{
+			{
+				VolumeID:     v.Volume.ID,
+				SnapshotName: name,
+			},
+		},
+	}
+
+	snapResp, err := system.CreateSnapshotConsistencyGroupCtx(
+		ctx, snapshotVolumesParam)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapResp.VolumeIDList) == 0 {
+		return nil, newNotFound("Couldn't find snapshot")
+	}
+
+	return v.getVolume(ctx, snapResp.VolumeIDList[0])
+}
+
+// ListSnapshots returns the snapshots taken of the volume, but not the
+// snapshots taken of those.
+func (v *Volume) ListSnapshots() ([]*types.Volume, error) {
+	return v.ListSnapshotsCtx(context.Background())
+}
+
+func (v *Volume) ListSnapshotsCtx(
+	ctx context.Context) ([]*types.Volume, error) {
+
+	if v.Volume.VTreeID == "" {
+		return nil, fmt.Errorf("%w: volume %s has no VTree",
+			ErrInvalidArgument, v.Volume.ID)
+	}
+
+	volumes, err := v.client.getVTreeVolumes(ctx, v.Volume.VTreeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*types.Volume
+	for _, volume := range volumes {
+		if volume.AncestorVolumeID == v.Volume.ID {
+			snapshots = append(snapshots, volume)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// GetParent returns the volume the volume is a snapshot of, or nil if it
+// is not a snapshot.
+func (v *Volume) GetParent() (*Volume, error) {
+	return v.GetParentCtx(context.Background())
+}
+
+func (v *Volume) GetParentCtx(ctx context.Context) (*Volume, error) {
+
+	if v.Volume.AncestorVolumeID == "" {
+		return nil, nil
+	}
+
+	return v.getVolume(ctx, v.Volume.AncestorVolumeID)
+}
+
+// GetSnapshotTree returns the tree of snapshots of the VTree the volume
+// belongs to, rooted at the VTree's base volume. A snapshot whose ancestor
+// is no longer in the VTree, because the ancestor was removed, is placed
+// directly below the base volume.
+func (v *Volume) GetSnapshotTree() (*SnapshotTree, error) {
+	return v.GetSnapshotTreeCtx(context.Background())
+}
+
+func (v *Volume) GetSnapshotTreeCtx(
+	ctx context.Context) (*SnapshotTree, error) {
+
+	vtree, err := v.GetVTreeCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := v.client.getVTreeVolumes(ctx, vtree.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep siblings in the order they were taken in
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return volumes[i].CreationTime < volumes[j].CreationTime
+	})
+
+	nodes := make(map[string]*SnapshotTree, len(volumes))
+	for _, volume := range volumes {
+		nodes[volume.ID] = &SnapshotTree{Volume: volume}
+	}
+
+	root, ok := nodes[vtree.BaseVolumeID]
+	if !ok {
+		return nil, newNotFound("Couldn't find base volume of VTree")
+	}
+
+	for _, volume := range volumes {
+		if volume.ID == vtree.BaseVolumeID {
+			continue
+		}
+		parent, ok := nodes[volume.AncestorVolumeID]
+		if !ok {
+			parent = root
+		}
+		parent.Snapshots = append(parent.Snapshots, nodes[volume.ID])
+	}
+
+	return root, nil
+}
+
+// getVTreeVolumes returns the base volume and every snapshot in the VTree
+// vtreeID.
+func (c *Client) getVTreeVolumes(
+	ctx context.Context, vtreeID string) ([]*types.Volume, error) {
+
+	path := fmt.Sprintf("/api/instances/VTree::%s/relationships/Volume",
+		vtreeID)
+
+	var volumes []*types.Volume
+	err := c.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
+func (v *Volume) getVolume(ctx context.Context, id string) (*Volume, error) {
+
+	path := fmt.Sprintf("/api/instances/Volume::%s", id)
+
+	volume := NewVolume(v.client)
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, volume.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return volume, nil
+}
+
+// OverwriteReport describes what overwriting a volume from a snapshot
+// affects.
+type OverwriteReport struct {
+	VolumeID   string
+	SnapshotID string
+	VTreeID    string
+
+	// MappedSdcs are the SDCs the volume is mapped to, which see its
+	// content change underneath them.
+	MappedSdcs []*types.MappedSdcInfo
+}
+
+// OverwriteFrom replaces the content of the volume with the content of
+// the snapshot snapshotID, which must be in the same VTree.
+func (v *Volume) OverwriteFrom(snapshotID string) error {
+	return v.OverwriteFromCtx(context.Background(), snapshotID)
+}
+
+func (v *Volume) OverwriteFromCtx(
+	ctx context.Context, snapshotID string) error {
+
+	if _, err := v.OverwriteFromDryRunCtx(ctx, snapshotID); err != nil {
+		return err
+	}
+
+	return v.client.overwriteVolumeContent(ctx, v.Volume.ID, snapshotID)
+}
+
+// OverwriteFromDryRun checks that the volume can be overwritten from the
+// snapshot snapshotID and reports the SDCs it is mapped to, without
+// changing it.
+func (v *Volume) OverwriteFromDryRun(
+	snapshotID string) (*OverwriteReport, error) {
+
+	return v.OverwriteFromDryRunCtx(context.Background(), snapshotID)
+}
+
+func (v *Volume) OverwriteFromDryRunCtx(
+	ctx context.Context, snapshotID string) (*OverwriteReport, error) {
+
+	if snapshotID == "" || snapshotID == v.Volume.ID {
+		return nil, fmt.Errorf("%w: volume %s cannot be overwritten from %q",
+			ErrInvalidArgument, v.Volume.ID, snapshotID)
+	}
+
+	// the mappings must be current for the report to be of any use, but
+	// a dry run leaves v.Volume as it is
+	current, err := v.getVolume(ctx, v.Volume.ID)
+	if err != nil {
+		return nil, err
+	}
+	volume := current.Volume
+
+	snapshot, err := v.getVolume(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.Volume.VTreeID != volume.VTreeID {
+		return nil, fmt.Errorf(
+			"%w: snapshot %s is in VTree %s, not in VTree %s of volume %s",
+			ErrInvalidArgument, snapshotID, snapshot.Volume.VTreeID,
+			volume.VTreeID, volume.ID)
+	}
+
+	return &OverwriteReport{
+		VolumeID:   volume.ID,
+		SnapshotID: snapshotID,
+		VTreeID:    volume.VTreeID,
+		MappedSdcs: volume.MappedSdcInfo,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package goscaleio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+// statisticsQueryChunkSize is the number of object IDs sent in one
+// querySelectedStatistics request.
+const statisticsQueryChunkSize = 500
+
+// StatisticsRecord holds the statistics of one object as decoded from
+// JSON, keyed by property name.
+type StatisticsRecord map[string]interface{}
+
+// StatisticsQuery gets selected statistics of many objects of one type,
+// e.g. every Sdc, in as few requests as possible.
+//
+//	stats := map[string]*types.VolumeStatistics{}
+//	err := client.NewStatisticsQuery("Volume").
+//		IDs(ids...).
+//		Properties("userDataReadBwc", "userDataWriteBwc").
+//		GetInto(&stats)
+type StatisticsQuery struct {
+	client     *Client
+	objType    string
+	ids        []string
+	allIDs     bool
+	properties []string
+}
+
+// NewStatisticsQuery returns a query for the statistics of objects of
+// objType, such as Sdc, Sds, Device, Volume, StoragePool or
+// ProtectionDomain.
+func (c *Client) NewStatisticsQuery(objType string) *StatisticsQuery {
+	return &StatisticsQuery{
+		client:  c,
+		objType: objType,
+	}
+}
+
+// IDs adds objects to the query.
+func (q *StatisticsQuery) IDs(ids ...string) *StatisticsQuery {
+	q.ids = append(q.ids, ids...)
+	return q
+}
+
+// AllIDs makes the query return the statistics of every object of its
+// type.
+func (q *StatisticsQuery) AllIDs() *StatisticsQuery {
+	q.allIDs = true
+	return q
+}
+
+// Properties adds statistics, e.g. userDataReadBwc, to the query.
+func (q *StatisticsQuery) Properties(properties ...string) *StatisticsQuery {
+	q.properties = append(q.properties, properties...)
+	return q
+}
+
+// Get returns the selected statistics keyed by object ID.
+func (q *StatisticsQuery) Get() (map[string]StatisticsRecord, error) {
+	return q.GetCtx(context.Background())
+}
+
+func (q *StatisticsQuery) GetCtx(
+	ctx context.Context) (map[string]StatisticsRecord, error) {
+
+	records := map[string]StatisticsRecord{}
+	if err := q.GetIntoCtx(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// GetInto decodes the selected statistics into out, which must be a
+// pointer to a map keyed by object ID, e.g. a
+// *map[string]*types.Statistics. Statistics that were not selected are
+// left at their zero value.
+func (q *StatisticsQuery) GetInto(out interface{}) error {
+	return q.GetIntoCtx(context.Background(), out)
+}
+
+func (q *StatisticsQuery) GetIntoCtx(
+	ctx context.Context, out interface{}) error {
+
+	if q.objType == "" || len(q.properties) == 0 ||
+		(!q.allIDs && len(q.ids) == 0) {
+		return fmt.Errorf(
+			"%w: a statistics query needs a type, properties and IDs",
+			ErrInvalidArgument)
+	}
+
+	path := fmt.Sprintf(
+		"/api/types/%s/instances/action/querySelectedStatistics",
+		q.objType)
+
+	if q.allIDs {
+		allIDs := ""
+		return q.client.getJSONWithRetry(
+			withQuery(ctx), http.MethodPost, path,
+			&types.QuerySelectedStatisticsParam{
+				AllIDs:     &allIDs,
+				Properties: q.properties,
+			}, out)
+	}
+
+	// every chunk is decoded into the same map, adding to its entries
+	for start := 0; start < len(q.ids); start += statisticsQueryChunkSize {
+		end := start + statisticsQueryChunkSize
+		if end > len(q.ids) {
+			end = len(q.ids)
+		}
+
+		err := q.client.getJSONWithRetry(
+			withQuery(ctx), http.MethodPost, path,
+			&types.QuerySelectedStatisticsParam{
+				IDs:        q.ids[start:end],
+				Properties: q.properties,
+			}, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,7 @@
 package goscaleio
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net/http"
 
@@ -28,6 +28,11 @@ func NewStoragePoolEx(client *Client, pool *types.StoragePool) *StoragePool {
 }
 
 func (pd *ProtectionDomain) CreateStoragePool(name string) (string, error) {
+	return pd.CreateStoragePoolCtx(context.Background(), name)
+}
+
+func (pd *ProtectionDomain) CreateStoragePoolCtx(
+	ctx context.Context, name string) (string, error) {
 
 	storagePoolParam := &types.StoragePoolParam{
 		Name:               name,
@@ -38,7 +43,7 @@ func (pd *ProtectionDomain) CreateStoragePool(name string) (string, error) {
 
 	sp := types.StoragePoolResp{}
 	err := pd.client.getJSONWithRetry(
-		http.MethodPost, path, storagePoolParam, &sp)
+		ctx, http.MethodPost, path, storagePoolParam, &sp)
 	if err != nil {
 		return "", err
 	}
@@ -49,6 +54,13 @@ func (pd *ProtectionDomain) CreateStoragePool(name string) (string, error) {
 func (pd *ProtectionDomain) GetStoragePool(
 	storagepoolhref string) ([]*types.StoragePool, error) {
 
+	return pd.GetStoragePoolCtx(context.Background(), storagepoolhref)
+}
+
+func (pd *ProtectionDomain) GetStoragePoolCtx(
+	ctx context.Context,
+	storagepoolhref string) ([]*types.StoragePool, error) {
+
 	var (
 		err error
 		sp  = &types.StoragePool{}
@@ -63,10 +75,10 @@ func (pd *ProtectionDomain) GetStoragePool(
 			return nil, err
 		}
 		err = pd.client.getJSONWithRetry(
-			http.MethodGet, link.HREF, nil, &sps)
+			ctx, http.MethodGet, link.HREF, nil, &sps)
 	} else {
 		err = pd.client.getJSONWithRetry(
-			http.MethodGet, storagepoolhref, nil, sp)
+			ctx, http.MethodGet, storagepoolhref, nil, sp)
 	}
 	if err != nil {
 		return nil, err
@@ -81,9 +93,16 @@ func (pd *ProtectionDomain) GetStoragePool(
 func (pd *ProtectionDomain) FindStoragePool(
 	id, name, href string) (*types.StoragePool, error) {
 
-	sps, err := pd.GetStoragePool(href)
+	return pd.FindStoragePoolCtx(context.Background(), id, name, href)
+}
+
+func (pd *ProtectionDomain) FindStoragePoolCtx(
+	ctx context.Context,
+	id, name, href string) (*types.StoragePool, error) {
+
+	sps, err := pd.GetStoragePoolCtx(ctx, href)
 	if err != nil {
-		return nil, fmt.Errorf("Error getting protection domains %s", err)
+		return nil, fmt.Errorf("Error getting protection domains %w", err)
 	}
 
 	for _, sp := range sps {
@@ -92,11 +111,16 @@ func (pd *ProtectionDomain) FindStoragePool(
 		}
 	}
 
-	return nil, errors.New("Couldn't find storage pool")
+	return nil, newNotFound("Couldn't find storage pool")
 
 }
 
 func (sp *StoragePool) GetStatistics() (*types.Statistics, error) {
+	return sp.GetStatisticsCtx(context.Background())
+}
+
+func (sp *StoragePool) GetStatisticsCtx(
+	ctx context.Context) (*types.Statistics, error) {
 
 	link, err := GetLink(sp.StoragePool.Links,
 		"/api/StoragePool/relationship/Statistics")
@@ -106,7 +130,7 @@ func (sp *StoragePool) GetStatistics() (*types.Statistics, error) {
 
 	stats := types.Statistics{}
 	err = sp.client.getJSONWithRetry(
-		http.MethodGet, link.HREF, nil, &stats)
+		ctx, http.MethodGet, link.HREF, nil, &stats)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package goscaleio
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -22,9 +23,16 @@ func NewSystem(client *Client) *System {
 func (c *Client) FindSystem(
 	instanceID, name, href string) (*System, error) {
 
-	systems, err := c.GetInstance(href)
+	return c.FindSystemCtx(context.Background(), instanceID, name, href)
+}
+
+func (c *Client) FindSystemCtx(
+	ctx context.Context,
+	instanceID, name, href string) (*System, error) {
+
+	systems, err := c.GetInstanceCtx(ctx, href)
 	if err != nil {
-		return nil, fmt.Errorf("err: problem getting instances: %s", err)
+		return nil, fmt.Errorf("err: problem getting instances: %w", err)
 	}
 
 	for _, system := range systems {
@@ -34,10 +42,15 @@ func (c *Client) FindSystem(
 			return outSystem, nil
 		}
 	}
-	return nil, fmt.Errorf("err: systemid or systemname not found")
+	return nil, newNotFound("err: systemid or systemname not found")
 }
 
 func (s *System) GetStatistics() (*types.Statistics, error) {
+	return s.GetStatisticsCtx(context.Background())
+}
+
+func (s *System) GetStatisticsCtx(
+	ctx context.Context) (*types.Statistics, error) {
 
 	link, err := GetLink(s.System.Links,
 		"/api/System/relationship/Statistics")
@@ -47,7 +60,7 @@ func (s *System) GetStatistics() (*types.Statistics, error) {
 
 	stats := types.Statistics{}
 	err = s.client.getJSONWithRetry(
-		http.MethodGet, link.HREF, nil, &stats)
+		ctx, http.MethodGet, link.HREF, nil, &stats)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +71,14 @@ func (s *System) GetStatistics() (*types.Statistics, error) {
 func (s *System) CreateSnapshotConsistencyGroup(
 	snapshotVolumesParam *types.SnapshotVolumesParam) (*types.SnapshotVolumesResp, error) {
 
+	return s.CreateSnapshotConsistencyGroupCtx(
+		context.Background(), snapshotVolumesParam)
+}
+
+func (s *System) CreateSnapshotConsistencyGroupCtx(
+	ctx context.Context,
+	snapshotVolumesParam *types.SnapshotVolumesParam) (*types.SnapshotVolumesResp, error) {
+
 	link, err := GetLink(s.System.Links, "self")
 	if err != nil {
 		return nil, err
@@ -67,10 +88,145 @@ func (s *System) CreateSnapshotConsistencyGroup(
 
 	snapResp := types.SnapshotVolumesResp{}
 	err = s.client.getJSONWithRetry(
-		http.MethodPost, path, snapshotVolumesParam, &snapResp)
+		ctx, http.MethodPost, path, snapshotVolumesParam, &snapResp)
 	if err != nil {
 		return nil, err
 	}
 
 	return &snapResp, nil
 }
+
+// GetSnapshotGroupVolumes returns the snapshots in the snapshot group
+// groupID, as returned by CreateSnapshotConsistencyGroup.
+func (s *System) GetSnapshotGroupVolumes(
+	groupID string) ([]*types.Volume, error) {
+
+	return s.GetSnapshotGroupVolumesCtx(context.Background(), groupID)
+}
+
+func (s *System) GetSnapshotGroupVolumesCtx(
+	ctx context.Context, groupID string) ([]*types.Volume, error) {
+
+	link, err := GetLink(s.System.Links, "self")
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("%v/relationships/Volume", link.HREF)
+
+	var volumes []*types.Volume
+	err = s.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*types.Volume
+	for _, volume := range volumes {
+		if groupID != "" && volume.ConsistencyGroupID == groupID {
+			snapshots = append(snapshots, volume)
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, newNotFound("Couldn't find snapshot group")
+	}
+
+	return snapshots, nil
+}
+
+// RemoveConsistencyGroupSnapshots removes every snapshot in the snapshot
+// group groupID.
+func (s *System) RemoveConsistencyGroupSnapshots(
+	groupID string) (*types.RemoveConsistencyGroupSnapshotsResp, error) {
+
+	return s.RemoveConsistencyGroupSnapshotsCtx(context.Background(), groupID)
+}
+
+func (s *System) RemoveConsistencyGroupSnapshotsCtx(
+	ctx context.Context,
+	groupID string) (*types.RemoveConsistencyGroupSnapshotsResp, error) {
+
+	link, err := GetLink(s.System.Links, "self")
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("%v/action/removeConsistencyGroupSnapshots",
+		link.HREF)
+
+	removeParam := &types.RemoveConsistencyGroupSnapshotsParam{
+		SnapGroupID: groupID,
+	}
+
+	removeResp := types.RemoveConsistencyGroupSnapshotsResp{}
+	err = s.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, removeParam, &removeResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &removeResp, nil
+}
+
+// RestoreSnapshotGroup overwrites the volumes the snapshots in the
+// snapshot group groupID were taken of with the content of the snapshots.
+// If volumeIDs are given only those volumes are restored, and they must
+// all have a snapshot in the group; otherwise every volume that still
+// exists is restored. Volumes are restored one at a time, so a failed
+// restore can leave the group partly applied; the error names the
+// volumes that were already restored.
+func (s *System) RestoreSnapshotGroup(
+	groupID string, volumeIDs ...string) error {
+
+	return s.RestoreSnapshotGroupCtx(context.Background(), groupID,
+		volumeIDs...)
+}
+
+func (s *System) RestoreSnapshotGroupCtx(
+	ctx context.Context, groupID string, volumeIDs ...string) error {
+
+	snapshots, err := s.GetSnapshotGroupVolumesCtx(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	// map every source volume to its snapshot in the group, leaving out
+	// snapshots whose source volume was removed
+	sources := make(map[string]string, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.AncestorVolumeID != "" {
+			sources[snapshot.AncestorVolumeID] = snapshot.ID
+		}
+	}
+
+	if len(volumeIDs) == 0 {
+		for _, snapshot := range snapshots {
+			if snapshot.AncestorVolumeID != "" {
+				volumeIDs = append(volumeIDs, snapshot.AncestorVolumeID)
+			}
+		}
+		if len(volumeIDs) == 0 {
+			return newNotFound(
+				"Couldn't find a volume to restore in snapshot group")
+		}
+	}
+	for _, volumeID := range volumeIDs {
+		if _, ok := sources[volumeID]; !ok {
+			return newNotFound(fmt.Sprintf(
+				"Couldn't find snapshot of volume %s in snapshot group",
+				volumeID))
+		}
+	}
+
+	for i, volumeID := range volumeIDs {
+		err := s.client.overwriteVolumeContent(
+			ctx, volumeID, sources[volumeID])
+		if err != nil {
+			return fmt.Errorf(
+				"problem restoring volume %s, after restoring %v: %w",
+				volumeID, volumeIDs[:i], err)
+		}
+	}
+
+	return nil
+}
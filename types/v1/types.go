@@ -54,6 +54,30 @@ type BWC struct {
 	NumSeconds      int `json:"numSeconds"`
 }
 
+// IOPS returns the number of operations per second.
+func (b BWC) IOPS() float64 {
+	if b.NumSeconds == 0 {
+		return 0
+	}
+	return float64(b.NumOccured) / float64(b.NumSeconds)
+}
+
+// BandwidthKBps returns the number of KB transferred per second.
+func (b BWC) BandwidthKBps() float64 {
+	if b.NumSeconds == 0 {
+		return 0
+	}
+	return float64(b.TotalWeightInKb) / float64(b.NumSeconds)
+}
+
+// AvgIOSizeKb returns the average size of an operation in KB.
+func (b BWC) AvgIOSizeKb() float64 {
+	if b.NumOccured == 0 {
+		return 0
+	}
+	return float64(b.TotalWeightInKb) / float64(b.NumOccured)
+}
+
 type Statistics struct {
 	PrimaryReadFromDevBwc                    BWC `json:"primaryReadFromDevBwc"`
 	NumOfStoragePools                        int `json:"numOfStoragePools"`
@@ -90,7 +114,7 @@ type Statistics struct {
 	DegradedFailedVacInKb                    int `json:"degradedFailedVacInKb"`
 	NumOfSnapshots                           int `json:"numOfSnapshots"`
 	RebalanceCapacityInKb                    int `json:"rebalanceCapacityInKb"`
-	fwdRebuildReadBwc                        BWC `json:"fwdRebuildReadBwc"`
+	FwdRebuildReadBwc                        BWC `json:"fwdRebuildReadBwc"`
 	NumOfSdc                                 int `json:"numOfSdc"`
 	ActiveMovingInFwdRebuildJobs             int `json:"activeMovingInFwdRebuildJobs"`
 	NumOfVtrees                              int `json:"numOfVtrees"`
@@ -132,6 +156,96 @@ type Statistics struct {
 	PrimaryWriteBwc                          BWC `json:"primaryWriteBwc"`
 }
 
+// StatisticsSummary holds the rates and capacity utilisation derived from
+// the raw counters of a System, StoragePool or Sdc. An Sdc has no
+// capacity, rebuild or rebalance figures.
+type StatisticsSummary struct {
+	ReadIOPS           float64
+	WriteIOPS          float64
+	ReadBandwidthKBps  float64
+	WriteBandwidthKBps float64
+
+	RebuildReadBandwidthKBps    float64
+	RebuildWriteBandwidthKBps   float64
+	RebalanceReadBandwidthKBps  float64
+	RebalanceWriteBandwidthKBps float64
+
+	// The capacities as a percentage of MaxCapacityInKb.
+	CapacityInUsePercent  float64
+	SpareCapacityPercent  float64
+	FailedCapacityPercent float64
+}
+
+// Summary returns the rates and capacity utilisation derived from s.
+func (s *Statistics) Summary() StatisticsSummary {
+	return StatisticsSummary{
+		ReadIOPS:           s.TotalReadBwc.IOPS(),
+		WriteIOPS:          s.TotalWriteBwc.IOPS(),
+		ReadBandwidthKBps:  s.TotalReadBwc.BandwidthKBps(),
+		WriteBandwidthKBps: s.TotalWriteBwc.BandwidthKBps(),
+
+		RebuildReadBandwidthKBps: s.FwdRebuildReadBwc.BandwidthKBps() +
+			s.BckRebuildReadBwc.BandwidthKBps(),
+		RebuildWriteBandwidthKBps: s.FwdRebuildWriteBwc.BandwidthKBps() +
+			s.BckRebuildWriteBwc.BandwidthKBps(),
+		RebalanceReadBandwidthKBps:  s.RebalanceReadBwc.BandwidthKBps(),
+		RebalanceWriteBandwidthKBps: s.RebalanceWriteBwc.BandwidthKBps(),
+
+		CapacityInUsePercent: percent(
+			s.CapacityInUseInKb, s.MaxCapacityInKb),
+		SpareCapacityPercent: percent(
+			s.SpareCapacityInKb, s.MaxCapacityInKb),
+		FailedCapacityPercent: percent(
+			s.FailedCapacityInKb, s.MaxCapacityInKb),
+	}
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
+
+type VolumeStatistics struct {
+	UserDataReadBwc        BWC      `json:"userDataReadBwc"`
+	UserDataWriteBwc       BWC      `json:"userDataWriteBwc"`
+	NumOfMappedSdcs        int      `json:"numOfMappedSdcs"`
+	MappedSdcIds           []string `json:"mappedSdcIds"`
+	NumOfChildVolumes      int      `json:"numOfChildVolumes"`
+	ChildVolumeIds         []string `json:"childVolumeIds"`
+	NumOfDescendantVolumes int      `json:"numOfDescendantVolumes"`
+	DescendantVolumeIds    []string `json:"descendantVolumeIds"`
+}
+
+// ReadIOPS returns the number of reads per second from the volume.
+func (s *VolumeStatistics) ReadIOPS() float64 {
+	return s.UserDataReadBwc.IOPS()
+}
+
+// WriteIOPS returns the number of writes per second to the volume.
+func (s *VolumeStatistics) WriteIOPS() float64 {
+	return s.UserDataWriteBwc.IOPS()
+}
+
+// ReadBandwidthKBps returns the KB read per second from the volume.
+func (s *VolumeStatistics) ReadBandwidthKBps() float64 {
+	return s.UserDataReadBwc.BandwidthKBps()
+}
+
+// WriteBandwidthKBps returns the KB written per second to the volume.
+func (s *VolumeStatistics) WriteBandwidthKBps() float64 {
+	return s.UserDataWriteBwc.BandwidthKBps()
+}
+
+// QuerySelectedStatisticsParam selects the objects in IDs, or every
+// object if AllIDs is set to any value.
+type QuerySelectedStatisticsParam struct {
+	IDs        []string `json:"ids,omitempty"`
+	AllIDs     *string  `json:"allIds,omitempty"`
+	Properties []string `json:"properties"`
+}
+
 type User struct {
 	SystemID              string  `json:"systemId"`
 	UserRole              string  `json:"userRole"`
@@ -177,11 +291,42 @@ type Sdc struct {
 	OnVmWare           bool    `json:"onVmWare"`
 	SdcGuid            string  `json:"sdcGuid"`
 	MdmConnectionState string  `json:"mdmConnectionState"`
+	PerfProfile        string  `json:"perfProfile"`
 	Name               string  `json:"name"`
 	ID                 string  `json:"id"`
 	Links              []*Link `json:"links"`
 }
 
+type SdcStatistics struct {
+	UserDataReadBwc    BWC      `json:"userDataReadBwc"`
+	UserDataWriteBwc   BWC      `json:"userDataWriteBwc"`
+	NumOfMappedVolumes int      `json:"numOfMappedVolumes"`
+	VolumeIds          []string `json:"volumeIds"`
+	MdmConnectionState string   `json:"mdmConnectionState"`
+}
+
+// Summary returns the rates derived from s.
+func (s *SdcStatistics) Summary() StatisticsSummary {
+	return StatisticsSummary{
+		ReadIOPS:           s.UserDataReadBwc.IOPS(),
+		WriteIOPS:          s.UserDataWriteBwc.IOPS(),
+		ReadBandwidthKBps:  s.UserDataReadBwc.BandwidthKBps(),
+		WriteBandwidthKBps: s.UserDataWriteBwc.BandwidthKBps(),
+	}
+}
+
+type ApproveSdcParam struct {
+	SdcGUID string `json:"sdcGuid"`
+}
+
+type SetSdcNameParam struct {
+	SdcName string `json:"sdcName"`
+}
+
+type SetSdcPerformanceParametersParam struct {
+	PerfProfile string `json:"perfProfile"`
+}
+
 type SdsIp struct {
 	IP   string `json:"ip"`
 	Role string `json:"role"`
@@ -229,7 +374,7 @@ type SdsParam struct {
 	NumOfIoBuffers     int           `json:"numOfIoBuffers,omitempty"`
 	DeviceInfoList     []*DeviceInfo `json:"deviceInfoList,omitempty"`
 	ForceClean         bool          `json:"forceClean,omitempty"`
-	DeviceTestTimeSecs int           `json:"deviceTestTimeSecs ,omitempty"`
+	DeviceTestTimeSecs int           `json:"deviceTestTimeSecs,omitempty"`
 	DeviceTestMode     string        `json:"deviceTestMode,omitempty"`
 }
 
@@ -237,6 +382,32 @@ type SdsResp struct {
 	ID string `json:"id"`
 }
 
+type RemoveSdsParam struct {
+	Force string `json:"force,omitempty"`
+}
+
+type SetSdsNameParam struct {
+	Name string `json:"name"`
+}
+
+type AddSdsIPParam struct {
+	IP   string `json:"ip"`
+	Role string `json:"role"`
+}
+
+type RemoveSdsIPParam struct {
+	IP string `json:"ip"`
+}
+
+type SetSdsIPRoleParam struct {
+	SdsIPToSet string `json:"sdsIpToSet"`
+	NewRole    string `json:"newRole"`
+}
+
+type SetSdsPortParam struct {
+	SdsPort string `json:"sdsPort"`
+}
+
 type Device struct {
 	ID                     string `json:"id,omitempty"`
 	Name                   string `json:"name,omitempty"`
@@ -323,6 +494,7 @@ type Volume struct {
 	VTreeID                 string           `json:"vtreeId"`
 	AncestorVolumeID        string           `json:"ancestorVolumeId"`
 	MappedScsiInitiatorInfo string           `json:"mappedScsiInitiatorInfo"`
+	AccessModeLimit         string           `json:"accessModeLimit"`
 	SizeInKb                int              `json:"sizeInKb"`
 	CreationTime            int              `json:"creationTime"`
 	Name                    string           `json:"name"`
@@ -343,6 +515,10 @@ type VolumeResp struct {
 	ID string `json:"id"`
 }
 
+type QueryIdByKeyParam struct {
+	Name string `json:"name"`
+}
+
 type VolumeQeryIdByKeyParam struct {
 	Name string `json:"name"`
 }
@@ -383,6 +559,18 @@ type SnapshotVolumesResp struct {
 	SnapshotGroupID string   `json:"snapshotGroupId"`
 }
 
+type RemoveConsistencyGroupSnapshotsParam struct {
+	SnapGroupID string `json:"snapGroupId"`
+}
+
+type RemoveConsistencyGroupSnapshotsResp struct {
+	NumberOfVolumes int `json:"numberOfVolumes"`
+}
+
+type OverwriteVolumeContentParam struct {
+	SrcVolumeID string `json:"srcVolumeId"`
+}
+
 type VTree struct {
 	ID            string  `json:"id"`
 	Name          string  `json:"name"`
@@ -394,3 +582,19 @@ type VTree struct {
 type RemoveVolumeParam struct {
 	RemoveMode string `json:"removeMode"`
 }
+
+type SetVolumeSizeParam struct {
+	SizeInGB string `json:"sizeInGB"`
+}
+
+type SetVolumeNameParam struct {
+	NewName string `json:"newName"`
+}
+
+type SetVolumeUseRmCacheParam struct {
+	UseRmCache string `json:"useRmcache"`
+}
+
+type SetVolumeAccessModeLimitParam struct {
+	AccessModeLimit string `json:"accessModeLimit"`
+}
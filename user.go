@@ -1,6 +1,7 @@
 package goscaleio
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -8,13 +9,17 @@ import (
 )
 
 func (s *System) GetUser() ([]types.User, error) {
+	return s.GetUserCtx(context.Background())
+}
+
+func (s *System) GetUserCtx(ctx context.Context) ([]types.User, error) {
 
 	path := fmt.Sprintf("/api/instances/System::%v/relationships/User",
 		s.System.ID)
 
 	var user []types.User
 	err := s.client.getJSONWithRetry(
-		http.MethodGet, path, nil, &user)
+		ctx, http.MethodGet, path, nil, &user)
 	if err != nil {
 		return nil, err
 	}
@@ -1,12 +1,15 @@
 package goscaleio
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	types "github.com/thecodeteam/goscaleio/types/v1"
@@ -33,10 +36,20 @@ func NewVolume(client *Client) *Volume {
 	}
 }
 
+// Deprecated: use ListVolumes.
 func (sp *StoragePool) GetVolume(
 	volumehref, volumeid, ancestorvolumeid, volumename string,
 	getSnapshots bool) ([]*types.Volume, error) {
 
+	return sp.GetVolumeCtx(context.Background(), volumehref, volumeid,
+		ancestorvolumeid, volumename, getSnapshots)
+}
+
+func (sp *StoragePool) GetVolumeCtx(
+	ctx context.Context,
+	volumehref, volumeid, ancestorvolumeid, volumename string,
+	getSnapshots bool) ([]*types.Volume, error) {
+
 	var (
 		err     error
 		path    string
@@ -45,12 +58,12 @@ func (sp *StoragePool) GetVolume(
 	)
 
 	if volumename != "" {
-		volumeid, err = sp.FindVolumeID(volumename)
-		if err != nil && err.Error() == "Not found" {
+		volumeid, err = sp.FindVolumeIDCtx(ctx, volumename)
+		if errors.Is(err, ErrNotFound) {
 			return nil, nil
 		}
 		if err != nil {
-			return nil, fmt.Errorf("Error: problem finding volume: %s", err)
+			return nil, fmt.Errorf("Error: problem finding volume: %w", err)
 		}
 	}
 
@@ -69,10 +82,10 @@ func (sp *StoragePool) GetVolume(
 
 	if volumehref == "" && volumeid == "" {
 		err = sp.client.getJSONWithRetry(
-			http.MethodGet, path, nil, &volumes)
+			ctx, http.MethodGet, path, nil, &volumes)
 	} else {
 		err = sp.client.getJSONWithRetry(
-			http.MethodGet, path, nil, volume)
+			ctx, http.MethodGet, path, nil, volume)
 	}
 	if err != nil {
 		return nil, err
@@ -93,6 +106,11 @@ func (sp *StoragePool) GetVolume(
 }
 
 func (sp *StoragePool) FindVolumeID(volumename string) (string, error) {
+	return sp.FindVolumeIDCtx(context.Background(), volumename)
+}
+
+func (sp *StoragePool) FindVolumeIDCtx(
+	ctx context.Context, volumename string) (string, error) {
 
 	volumeQeryIdByKeyParam := &types.VolumeQeryIdByKeyParam{
 		Name: volumename,
@@ -101,7 +119,8 @@ func (sp *StoragePool) FindVolumeID(volumename string) (string, error) {
 	path := fmt.Sprintf("/api/types/Volume/instances/action/queryIdByKey")
 
 	volumeID, err := sp.client.getStringWithRetry(
-		http.MethodPost, path, volumeQeryIdByKeyParam)
+		withQuery(ctx), http.MethodPost, path,
+		volumeQeryIdByKeyParam)
 	if err != nil {
 		return "", err
 	}
@@ -142,6 +161,13 @@ func GetLocalVolumeMap() (mappedVolumes []*SdcMappedVolume, err error) {
 func (sp *StoragePool) CreateVolume(
 	volume *types.VolumeParam) (*types.VolumeResp, error) {
 
+	return sp.CreateVolumeCtx(context.Background(), volume)
+}
+
+func (sp *StoragePool) CreateVolumeCtx(
+	ctx context.Context,
+	volume *types.VolumeParam) (*types.VolumeResp, error) {
+
 	path := "/api/types/Volume/instances"
 
 	volume.StoragePoolID = sp.StoragePool.ID
@@ -149,7 +175,7 @@ func (sp *StoragePool) CreateVolume(
 
 	volumeResp := &types.VolumeResp{}
 	err := sp.client.getJSONWithRetry(
-		http.MethodPost, path, volume, volumeResp)
+		ctx, http.MethodPost, path, volume, volumeResp)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +184,10 @@ func (sp *StoragePool) CreateVolume(
 }
 
 func (v *Volume) GetVTree() (*types.VTree, error) {
+	return v.GetVTreeCtx(context.Background())
+}
+
+func (v *Volume) GetVTreeCtx(ctx context.Context) (*types.VTree, error) {
 
 	link, err := GetLink(v.Volume.Links, "/api/parent/relationship/vtreeId")
 	if err != nil {
@@ -166,7 +196,7 @@ func (v *Volume) GetVTree() (*types.VTree, error) {
 
 	vtree := &types.VTree{}
 	err = v.client.getJSONWithRetry(
-		http.MethodGet, link.HREF, nil, vtree)
+		ctx, http.MethodGet, link.HREF, nil, vtree)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +204,32 @@ func (v *Volume) GetVTree() (*types.VTree, error) {
 	return vtree, nil
 }
 
+func (v *Volume) GetStatistics() (*types.VolumeStatistics, error) {
+	return v.GetStatisticsCtx(context.Background())
+}
+
+func (v *Volume) GetStatisticsCtx(
+	ctx context.Context) (*types.VolumeStatistics, error) {
+
+	path := fmt.Sprintf("/api/instances/Volume::%s/relationships/Statistics",
+		v.Volume.ID)
+
+	stats := types.VolumeStatistics{}
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodGet, path, nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
 func (v *Volume) RemoveVolume(removeMode string) error {
+	return v.RemoveVolumeCtx(context.Background(), removeMode)
+}
+
+func (v *Volume) RemoveVolumeCtx(
+	ctx context.Context, removeMode string) error {
 
 	link, err := GetLink(v.Volume.Links, "self")
 	if err != nil {
@@ -191,6 +246,157 @@ func (v *Volume) RemoveVolume(removeMode string) error {
 	}
 
 	err = v.client.getJSONWithRetry(
-		http.MethodPost, path, removeVolumeParam, nil)
+		ctx, http.MethodPost, path, removeVolumeParam, nil)
 	return err
 }
+
+// VolumeSizeGranularityGB is the allocation granularity of volumes; their
+// size must be a multiple of it.
+const VolumeSizeGranularityGB = 8
+
+// The access mode limits a volume can be set to.
+const (
+	VolumeAccessModeReadWrite = "ReadWrite"
+	VolumeAccessModeReadOnly  = "ReadOnly"
+)
+
+// SetVolumeSize grows the volume to sizeInGB, which must be a multiple of
+// VolumeSizeGranularityGB and not smaller than the volume.
+func (v *Volume) SetVolumeSize(sizeInGB int) error {
+	return v.SetVolumeSizeCtx(context.Background(), sizeInGB)
+}
+
+func (v *Volume) SetVolumeSizeCtx(ctx context.Context, sizeInGB int) error {
+
+	if sizeInGB <= 0 || sizeInGB%VolumeSizeGranularityGB != 0 {
+		return fmt.Errorf(
+			"%w: volume size %d GB is not a positive multiple of %d GB",
+			ErrInvalidArgument, sizeInGB, VolumeSizeGranularityGB)
+	}
+	sizeInKb := sizeInGB * 1024 * 1024
+	if sizeInKb < v.Volume.SizeInKb {
+		return fmt.Errorf(
+			"%w: volume size %d GB is smaller than current size %d KB",
+			ErrInvalidArgument, sizeInGB, v.Volume.SizeInKb)
+	}
+
+	path := fmt.Sprintf("/api/instances/Volume::%s/action/setVolumeSize",
+		v.Volume.ID)
+
+	setVolumeSizeParam := &types.SetVolumeSizeParam{
+		SizeInGB: strconv.Itoa(sizeInGB),
+	}
+
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, setVolumeSizeParam, nil)
+	if err != nil {
+		return err
+	}
+
+	v.Volume.SizeInKb = sizeInKb
+	return nil
+}
+
+func (v *Volume) SetVolumeName(newName string) error {
+	return v.SetVolumeNameCtx(context.Background(), newName)
+}
+
+func (v *Volume) SetVolumeNameCtx(ctx context.Context, newName string) error {
+
+	if newName == "" {
+		return fmt.Errorf("%w: volume name is required", ErrInvalidArgument)
+	}
+
+	path := fmt.Sprintf("/api/instances/Volume::%s/action/setVolumeName",
+		v.Volume.ID)
+
+	setVolumeNameParam := &types.SetVolumeNameParam{
+		NewName: newName,
+	}
+
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, setVolumeNameParam, nil)
+	if err != nil {
+		return err
+	}
+
+	v.Volume.Name = newName
+	return nil
+}
+
+func (v *Volume) SetVolumeUseRmCache(useRmCache bool) error {
+	return v.SetVolumeUseRmCacheCtx(context.Background(), useRmCache)
+}
+
+func (v *Volume) SetVolumeUseRmCacheCtx(
+	ctx context.Context, useRmCache bool) error {
+
+	path := fmt.Sprintf(
+		"/api/instances/Volume::%s/action/setVolumeUseRmcache",
+		v.Volume.ID)
+
+	setVolumeUseRmCacheParam := &types.SetVolumeUseRmCacheParam{
+		UseRmCache: strings.ToUpper(strconv.FormatBool(useRmCache)),
+	}
+
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, setVolumeUseRmCacheParam, nil)
+	if err != nil {
+		return err
+	}
+
+	v.Volume.UseRmCache = useRmCache
+	return nil
+}
+
+// SetVolumeAccessModeLimit limits the access mode of every mapping of the
+// volume to accessMode, VolumeAccessModeReadWrite or
+// VolumeAccessModeReadOnly.
+func (v *Volume) SetVolumeAccessModeLimit(accessMode string) error {
+	return v.SetVolumeAccessModeLimitCtx(context.Background(), accessMode)
+}
+
+func (v *Volume) SetVolumeAccessModeLimitCtx(
+	ctx context.Context, accessMode string) error {
+
+	switch accessMode {
+	case VolumeAccessModeReadWrite, VolumeAccessModeReadOnly:
+	default:
+		return fmt.Errorf("%w: unknown access mode %q",
+			ErrInvalidArgument, accessMode)
+	}
+
+	path := fmt.Sprintf(
+		"/api/instances/Volume::%s/action/setVolumeAccessModeLimit",
+		v.Volume.ID)
+
+	setVolumeAccessModeLimitParam := &types.SetVolumeAccessModeLimitParam{
+		AccessModeLimit: accessMode,
+	}
+
+	err := v.client.getJSONWithRetry(
+		ctx, http.MethodPost, path, setVolumeAccessModeLimitParam, nil)
+	if err != nil {
+		return err
+	}
+
+	v.Volume.AccessModeLimit = accessMode
+	return nil
+}
+
+// overwriteVolumeContent replaces the content of the volume volumeID with
+// the content of the volume srcVolumeID, which must be in the same VTree.
+func (c *Client) overwriteVolumeContent(
+	ctx context.Context, volumeID, srcVolumeID string) error {
+
+	path := fmt.Sprintf(
+		"/api/instances/Volume::%s/action/overwriteVolumeContent",
+		volumeID)
+
+	overwriteVolumeContentParam := &types.OverwriteVolumeContentParam{
+		SrcVolumeID: srcVolumeID,
+	}
+
+	return c.getJSONWithRetry(
+		ctx, http.MethodPost, path, overwriteVolumeContentParam, nil)
+}
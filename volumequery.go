@@ -0,0 +1,158 @@
+package goscaleio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	types "github.com/thecodeteam/goscaleio/types/v1"
+)
+
+// The types a volume can have.
+const (
+	VolumeTypeThick    = "ThickProvisioned"
+	VolumeTypeThin     = "ThinProvisioned"
+	VolumeTypeSnapshot = "Snapshot"
+)
+
+// VolumeQuery selects the volumes returned by ListVolumes. A volume must
+// match every field that is set. The most specific of VTreeID, SdcID and
+// StoragePoolID decides which volumes are requested from the gateway; all
+// other fields are matched by the client.
+type VolumeQuery struct {
+	StoragePoolID      string
+	ProtectionDomainID string
+	VTreeID            string
+
+	// SdcID selects the volumes mapped to the SDC.
+	SdcID string
+
+	// VolumeType is one of VolumeTypeThick, VolumeTypeThin and
+	// VolumeTypeSnapshot.
+	VolumeType string
+
+	NamePrefix string
+	NameRegexp *regexp.Regexp
+
+	// MinSizeInKb and MaxSizeInKb bound the size of the volumes. Zero
+	// means unbounded.
+	MinSizeInKb int
+	MaxSizeInKb int
+}
+
+// path returns the path of the smallest list of volumes that includes
+// every volume matching the query.
+func (q *VolumeQuery) path() string {
+	switch {
+	case q.VTreeID != "":
+		return fmt.Sprintf("/api/instances/VTree::%s/relationships/Volume",
+			q.VTreeID)
+	case q.SdcID != "":
+		return fmt.Sprintf("/api/instances/Sdc::%s/relationships/Volume",
+			q.SdcID)
+	case q.StoragePoolID != "":
+		return fmt.Sprintf(
+			"/api/instances/StoragePool::%s/relationships/Volume",
+			q.StoragePoolID)
+	}
+	return "/api/types/Volume/instances"
+}
+
+// match reports whether volume matches the query. pools are the storage
+// pools of the query's protection domain.
+func (q *VolumeQuery) match(
+	volume *types.Volume, pools map[string]bool) bool {
+
+	switch {
+	case q.StoragePoolID != "" && volume.StoragePoolID != q.StoragePoolID,
+		q.ProtectionDomainID != "" && !pools[volume.StoragePoolID],
+		q.VTreeID != "" && volume.VTreeID != q.VTreeID,
+		q.VolumeType != "" && volume.VolumeType != q.VolumeType,
+		!strings.HasPrefix(volume.Name, q.NamePrefix),
+		q.NameRegexp != nil && !q.NameRegexp.MatchString(volume.Name),
+		q.MinSizeInKb > 0 && volume.SizeInKb < q.MinSizeInKb,
+		q.MaxSizeInKb > 0 && volume.SizeInKb > q.MaxSizeInKb:
+		return false
+	}
+
+	if q.SdcID != "" {
+		for _, info := range volume.MappedSdcInfo {
+			if info.SdcID == q.SdcID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// ListVolumes returns the volumes that match q, or every volume if q is
+// nil.
+func (c *Client) ListVolumes(q *VolumeQuery) ([]*Volume, error) {
+	return c.ListVolumesCtx(context.Background(), q)
+}
+
+func (c *Client) ListVolumesCtx(
+	ctx context.Context, q *VolumeQuery) ([]*Volume, error) {
+
+	if q == nil {
+		q = &VolumeQuery{}
+	}
+
+	var pools map[string]bool
+	if q.ProtectionDomainID != "" {
+		path := fmt.Sprintf(
+			"/api/instances/ProtectionDomain::%s/relationships/StoragePool",
+			q.ProtectionDomainID)
+
+		var storagePools []*types.StoragePool
+		err := c.getJSONWithRetry(
+			ctx, http.MethodGet, path, nil, &storagePools)
+		if err != nil {
+			return nil, err
+		}
+
+		pools = make(map[string]bool, len(storagePools))
+		for _, storagePool := range storagePools {
+			pools[storagePool.ID] = true
+		}
+	}
+
+	var volumes []*types.Volume
+	err := c.getJSONWithRetry(
+		ctx, http.MethodGet, q.path(), nil, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*Volume
+	for _, volume := range volumes {
+		if q.match(volume, pools) {
+			outVolume := NewVolume(c)
+			outVolume.Volume = volume
+			matched = append(matched, outVolume)
+		}
+	}
+
+	return matched, nil
+}
+
+// ListVolumes returns the volumes in the storage pool that match q.
+func (sp *StoragePool) ListVolumes(q *VolumeQuery) ([]*Volume, error) {
+	return sp.ListVolumesCtx(context.Background(), q)
+}
+
+func (sp *StoragePool) ListVolumesCtx(
+	ctx context.Context, q *VolumeQuery) ([]*Volume, error) {
+
+	spq := VolumeQuery{}
+	if q != nil {
+		spq = *q
+	}
+	spq.StoragePoolID = sp.StoragePool.ID
+
+	return sp.client.ListVolumesCtx(ctx, &spq)
+}